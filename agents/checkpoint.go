@@ -0,0 +1,187 @@
+package agents
+
+import (
+	"fmt"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+// A FirmState is everything a firm carries from one iteration to the next, for
+// saving a simulation part way through.
+type FirmState struct {
+	Good          goods.Good   `json:"good"`
+	Wage          market.Price `json:"wage"`
+	Price         market.Price `json:"price"`
+	WorkersHired  market.Size  `json:"workers_hired"`
+	TargetWorkers market.Size  `json:"target_workers"`
+	SalesMade     market.Size  `json:"sales_made"`
+	TargetSales   market.Size  `json:"target_sales"`
+	Money         market.Price `json:"money"`
+	Inventory     market.Size  `json:"inventory"`
+	Revenue       market.Price `json:"revenue"`
+	WageBill      market.Price `json:"wage_bill"`
+	// Who is under contract to work for the firm, as positions in the list of
+	// workers, in the order they were hired.
+	Employees []int `json:"employees,omitempty"`
+	// How many iterations in a row the firm has made losses, if firms can exit.
+	LossStreak int `json:"loss_streak,omitempty"`
+	Rejected   int `json:"rejected,omitempty"`
+}
+
+// A WorkerState is everything a worker carries from one iteration to the
+// next, for saving a simulation part way through.
+type WorkerState struct {
+	Unemployed    bool                        `json:"unemployed"`
+	Wage          market.Price                `json:"wage"`
+	Prices        map[goods.Good]market.Price `json:"prices"`
+	Demand        map[goods.Good]market.Size  `json:"demand"`
+	PurchasesMade map[goods.Good]market.Size  `json:"purchases_made"`
+	Money         market.Price                `json:"money"`
+	Contract      *ContractState              `json:"contract,omitempty"`
+	Rejected      int                         `json:"rejected,omitempty"`
+}
+
+// A ContractState is a saved employment contract.
+type ContractState struct {
+	// The firm the worker is under contract with, as a position in the list
+	// of firms.
+	Employer int          `json:"employer"`
+	Wage     market.Price `json:"wage"`
+	Age      int          `json:"age"`
+}
+
+// Save gets the state of every firm and worker. Firms and workers refer to
+// each other by their positions in the lists, so they have to be restored in
+// the same order. e can be nil if firms don't enter or exit.
+func Save(firms []*Firm, workers []*Worker, e *EntryExit) ([]FirmState, []WorkerState) {
+	firmIndex := map[*Firm]int{}
+	for i, f := range firms {
+		firmIndex[f] = i
+	}
+	workerIndex := map[*Worker]int{}
+	for i, w := range workers {
+		workerIndex[w] = i
+	}
+
+	fs := make([]FirmState, len(firms))
+	for i, f := range firms {
+		fs[i] = FirmState{
+			Good:          f.goodProduced,
+			Wage:          f.wage,
+			Price:         f.price,
+			WorkersHired:  f.workersHired,
+			TargetWorkers: f.targetWorkers,
+			SalesMade:     f.salesMade,
+			TargetSales:   f.targetSales,
+			Money:         f.money,
+			Inventory:     f.inventory,
+			Revenue:       f.revenue,
+			WageBill:      f.wageBill,
+			Rejected:      f.rejected,
+		}
+		for _, w := range f.employees {
+			fs[i].Employees = append(fs[i].Employees, workerIndex[w])
+		}
+		if e != nil {
+			fs[i].LossStreak = e.lossStreaks[f]
+		}
+	}
+
+	ws := make([]WorkerState, len(workers))
+	for i, w := range workers {
+		ws[i] = WorkerState{
+			Unemployed:    w.unemployed,
+			Wage:          w.wage,
+			Prices:        map[goods.Good]market.Price{},
+			Demand:        map[goods.Good]market.Size{},
+			PurchasesMade: map[goods.Good]market.Size{},
+			Money:         w.money,
+			Rejected:      w.rejected,
+		}
+		for good, price := range w.prices {
+			ws[i].Prices[good] = price
+		}
+		for good, size := range w.demand {
+			ws[i].Demand[good] = size
+		}
+		for good, size := range w.purchasesMade {
+			ws[i].PurchasesMade[good] = size
+		}
+		if c := w.contract; c != nil {
+			ws[i].Contract = &ContractState{Employer: firmIndex[c.employer], Wage: c.wage, Age: c.age}
+		}
+	}
+	return fs, ws
+}
+
+// Restore rebuilds firms and workers from their saved states, see Save. It's
+// an error if they refer to firms or workers that aren't there.
+func Restore(p *Parameters, e *EntryExit, fs []FirmState, ws []WorkerState) ([]*Firm, []*Worker, error) {
+	firms := make([]*Firm, len(fs))
+	for i, s := range fs {
+		firms[i] = &Firm{
+			goodProduced:  s.Good,
+			wage:          s.Wage,
+			price:         s.Price,
+			workersHired:  s.WorkersHired,
+			targetWorkers: s.TargetWorkers,
+			salesMade:     s.SalesMade,
+			targetSales:   s.TargetSales,
+			money:         s.Money,
+			inventory:     s.Inventory,
+			revenue:       s.Revenue,
+			wageBill:      s.WageBill,
+			employment:    p.Employment,
+			rejected:      s.Rejected,
+		}
+		if e != nil && s.LossStreak > 0 {
+			if e.lossStreaks == nil {
+				e.lossStreaks = map[*Firm]int{}
+			}
+			e.lossStreaks[firms[i]] = s.LossStreak
+		}
+	}
+
+	workers := make([]*Worker, len(ws))
+	for i, s := range ws {
+		w := &Worker{
+			unemployed:    s.Unemployed,
+			wage:          s.Wage,
+			prices:        s.Prices,
+			demand:        s.Demand,
+			purchasesMade: s.PurchasesMade,
+			money:         s.Money,
+			rejected:      s.Rejected,
+		}
+		if w.prices == nil {
+			w.prices = map[goods.Good]market.Price{}
+		}
+		if w.demand == nil {
+			w.demand = map[goods.Good]market.Size{}
+		}
+		if w.purchasesMade == nil {
+			w.purchasesMade = map[goods.Good]market.Size{}
+		}
+		if c := s.Contract; c != nil {
+			if c.Employer < 0 || c.Employer >= len(firms) {
+				return nil, nil, fmt.Errorf("worker %d works for firm %d, which doesn't exist", i, c.Employer)
+			}
+			w.contract = &contract{employer: firms[c.Employer], wage: c.Wage, age: c.Age}
+		}
+		workers[i] = w
+	}
+
+	for i, s := range fs {
+		for _, j := range s.Employees {
+			if j < 0 || j >= len(workers) {
+				return nil, nil, fmt.Errorf("firm %d employs worker %d, who doesn't exist", i, j)
+			}
+			if c := workers[j].contract; c == nil || c.employer != firms[i] {
+				return nil, nil, fmt.Errorf("firm %d employs worker %d, who isn't under contract with it", i, j)
+			}
+			firms[i].employees = append(firms[i].employees, workers[j])
+		}
+	}
+	return firms, workers, nil
+}
@@ -0,0 +1,55 @@
+package agents
+
+// A Phase is a part of a cycle that agents act in.
+type Phase int
+
+const (
+	// SinglePhase is a cycle where agents do everything in a single pass.
+	SinglePhase Phase = iota
+
+	// The phases of a cycle that is split up, in the order they happen.
+
+	// LabourPhase is when firms and workers set their wages and go to the
+	// labour market.
+	LabourPhase
+	// ProductionPhase is when firms make goods with the workers they just
+	// hired.
+	ProductionPhase
+	// GoodsPhase is when firms put their stock up for sale and workers spend
+	// what they were just paid on goods.
+	GoodsPhase
+	// ConsumptionPhase is when firms and workers look at how the goods markets
+	// went and adjust their prices for next time.
+	ConsumptionPhase
+)
+
+func (p Phase) String() string {
+	switch p {
+	case SinglePhase:
+		return "Single"
+	case LabourPhase:
+		return "Labour"
+	case ProductionPhase:
+		return "Production"
+	case GoodsPhase:
+		return "Goods"
+	case ConsumptionPhase:
+		return "Consumption"
+	}
+	return "Unknown"
+}
+
+// A Clock tells agents where the simulation is up to when they act.
+type Clock struct {
+	// How many cycles have been run before this one, so 0 in the first.
+	Cycle int
+	// Which part of the cycle it is.
+	Phase Phase
+	// How much simulated time has passed since the start, in cycles: the
+	// cycle, plus how far through it the phase is.
+	Elapsed float64
+}
+
+// First checks whether this is the first cycle, when agents haven't been to
+// market yet and have nothing to adjust their prices from.
+func (c Clock) First() bool { return c.Cycle == 0 }
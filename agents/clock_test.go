@@ -0,0 +1,63 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+func TestPhases(t *testing.T) {
+	p := &Parameters{
+		Increment:     1,
+		Elasticity:    0.8,
+		DissavingRate: 0.5,
+		LabourMarket:  market.NewDoubleAuction(goods.Labour),
+		Goods: map[goods.Good]GoodParameters{
+			goods.Grain: {Tech: 100, Scale: 0.5, Share: 1, Market: market.NewDoubleAuction(goods.Grain)},
+		},
+	}
+	f := NewFirm(goods.Grain, 10, 2, 1000)
+	w := NewWorker(10, 2, 0)
+	act := func(phase Phase) {
+		c := Clock{Phase: phase}
+		f.Act(p, c)
+		w.Act(p, c)
+	}
+
+	// The worker is hired and paid before anything else happens.
+	act(LabourPhase)
+	p.LabourMarket.Reset()
+	if w.Unemployed() || w.Money() != 10 {
+		t.Fatalf("after the labour market: got unemployed %t with %s, want employed with 10", w.Unemployed(), w.Money())
+	}
+	if f.Inventory() != 0 {
+		t.Errorf("firm had %s in stock before producing", f.Inventory())
+	}
+
+	// The firm produces 100 * 1^0.5 with the worker it just hired.
+	act(ProductionPhase)
+	if got, want := f.Inventory(), market.Size(100); got != want {
+		t.Fatalf("after production: got %s in stock, want %s", got, want)
+	}
+
+	// The worker spends their whole wage on grain.
+	act(GoodsPhase)
+	p.Goods[goods.Grain].Market.Reset()
+	if got, want := w.Purchases(goods.Grain), market.Size(5); got != want {
+		t.Errorf("after the goods market: worker bought %s, want %s", got, want)
+	}
+	if got, want := f.Inventory(), market.Size(95); got != want {
+		t.Errorf("after the goods market: firm has %s in stock, want %s", got, want)
+	}
+
+	// With stock left over and no bids, the firm lowers its price; and having
+	// got everything they wanted, so does the worker.
+	act(ConsumptionPhase)
+	if f.Price() != 1 {
+		t.Errorf("after consumption: got firm price %s, want 1", f.Price())
+	}
+	if w.prices[goods.Grain] != 1 {
+		t.Errorf("after consumption: got worker price %s, want 1", w.prices[goods.Grain])
+	}
+}
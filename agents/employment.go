@@ -0,0 +1,171 @@
+package agents
+
+import (
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+// Employment turns labour market fills into lasting employment contracts.
+// Without it, every worker goes back on the labour market every iteration.
+//
+// With it, a worker hired by a firm keeps working there at the contract wage,
+// paid every iteration, until the worker quits for a better paying job, the
+// firm lays the worker off, or the contract runs out. Only unemployed workers
+// and workers that have seen a higher wage on offer look for work.
+type Employment struct {
+	// How many iterations a contract lasts, or 0 for contracts that last until
+	// the worker quits or is laid off.
+	Duration int
+
+	events EmploymentEvents
+}
+
+// EmploymentEvents counts what happened in the labour market.
+type EmploymentEvents struct {
+	// How many workers firms were looking for.
+	Vacancies int `json:"vacancies"`
+	// How many new contracts were made.
+	Hires int `json:"hires"`
+	// How many workers left for another job.
+	Quits int `json:"quits"`
+	// How many workers were let go by their firm.
+	Layoffs int `json:"layoffs"`
+	// How many contracts ran out.
+	Expiries int `json:"expiries"`
+}
+
+// Events gets what has happened since the last call to ResetEvents.
+func (e *Employment) Events() EmploymentEvents { return e.events }
+
+// ResetEvents starts counting events from zero again.
+func (e *Employment) ResetEvents() { e.events = EmploymentEvents{} }
+
+// A contract is an agreement for a worker to work at a firm for a wage.
+type contract struct {
+	employer *Firm
+	wage     market.Price
+	// How many iterations the contract has been paid for.
+	age int
+	// The cycle the employer's payroll last paid the worker in. Once they have
+	// been paid for a cycle they stay until the next one, so that they can't be
+	// paid again by a new employer.
+	paid int
+}
+
+// Employees gets the number of workers currently under contract with this firm.
+func (f *Firm) Employees() int { return len(f.employees) }
+
+// OnMatch is triggered when the firm hires a worker. With employment
+// contracts, the worker becomes an employee of this firm.
+func (f *Firm) OnMatch(good goods.Good, side market.Side, id market.OrderID, counterparty market.MarketAgent, wage market.Price, size market.Size) {
+	w, ok := counterparty.(*Worker)
+	if f.employment == nil || good != goods.Labour || !ok {
+		return
+	}
+
+	w.search = nil
+	if w.contract != nil && w.contract.employer == f {
+		// Payroll gives raises to employees the firm would hire for what they're
+		// asking, so this shouldn't happen. If it does, the firm has paid the new
+		// wage instead of holding back the old one, so it's just a raise.
+		w.contract.wage = wage
+		return
+	}
+	if w.contract != nil {
+		w.contract.employer.release(w)
+		f.employment.events.Quits++
+	}
+	// Hiring pays the first iteration of the contract.
+	w.contract = &contract{employer: f, wage: wage, age: 1}
+	w.unemployed = false
+	f.employees = append(f.employees, w)
+	f.employment.events.Hires++
+}
+
+// release removes a worker from this firm's employees.
+func (f *Firm) release(w *Worker) {
+	for i, e := range f.employees {
+		if e == w {
+			f.employees = append(f.employees[:i], f.employees[i+1:]...)
+			break
+		}
+	}
+	w.contract = nil
+	w.unemployed = true
+}
+
+// layOff lets go of the most recently hired workers until only n are left.
+func (f *Firm) layOff(n int) {
+	for len(f.employees) > n {
+		f.release(f.employees[len(f.employees)-1])
+		f.employment.events.Layoffs++
+	}
+}
+
+// payroll pays all of this firm's employees their contract wage. Contracts
+// that have run out end, and if the firm can't pay someone they are laid off.
+//
+// Employees looking for a better job aren't paid yet: whoever hires them pays
+// them, and if nobody does the firm pays them once the labour market closes.
+// If the firm is now hiring at what they are asking for, it gives them a raise
+// instead.
+func (f *Firm) payroll(p *Parameters, clock Clock) {
+	if n := f.targetWorkers.Units(); n < len(f.employees) {
+		f.layOff(n)
+	}
+
+	// What's been held back so far, which the firm can't spend on anyone else.
+	held := market.Price(0)
+	// Copy, since releasing workers changes the list.
+	for _, w := range append([]*Worker(nil), f.employees...) {
+		c := w.contract
+		if p.Employment.Duration > 0 && c.age >= p.Employment.Duration {
+			f.release(w)
+			p.Employment.events.Expiries++
+			continue
+		}
+		available := f.money.Sub(held)
+		if w.search != nil && f.wage >= w.search.Price && available >= f.wage {
+			// The firm would hire them for what they're asking, so a raise keeps
+			// them without going through the labour market.
+			p.LabourMarket.Cancel(w.search.ID)
+			w.search = nil
+			c.wage = f.wage
+		}
+		if available < c.wage {
+			f.release(w)
+			p.Employment.events.Layoffs++
+			continue
+		}
+		if w.search != nil {
+			held = held.Add(c.wage)
+			continue
+		}
+
+		c.paid = clock.Cycle
+		f.pay(w)
+	}
+}
+
+// pay pays an employee their contract wage for this iteration.
+func (f *Firm) pay(w *Worker) {
+	c := w.contract
+	c.age++
+	debit(&f.money, c.wage, "firm paying wages")
+	f.wageBill = f.wageBill.Add(c.wage)
+	f.workersHired = f.workersHired.Add(market.Units(1))
+	w.money = w.money.Add(c.wage)
+	w.unemployed = false
+}
+
+// held gets the wages the firm is holding back for employees looking for a
+// better job.
+func (f *Firm) held() market.Price {
+	held := market.Price(0)
+	for _, w := range f.employees {
+		if w.search != nil {
+			held = held.Add(w.contract.wage)
+		}
+	}
+	return held
+}
@@ -0,0 +1,186 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+func employmentParameters(duration int) *Parameters {
+	p := &Parameters{
+		Increment:    1,
+		Elasticity:   0.8,
+		LabourMarket: market.NewDoubleAuction(goods.Labour),
+		Goods:        map[goods.Good]GoodParameters{},
+		Employment:   &Employment{Duration: duration},
+	}
+	for _, good := range goods.AllGoods {
+		p.Goods[good] = GoodParameters{Tech: 1000, Scale: 0.5, Share: 1, Market: market.NewDoubleAuction(good)}
+	}
+	return p
+}
+
+// hire makes w an employee of f, as if they had traded on the labour market.
+func hire(f *Firm, w *Worker, wage market.Price) {
+	f.OnFill(goods.Labour, market.Buy, 1, wage, 1)
+	w.OnFill(goods.Labour, market.Sell, 2, wage, 1)
+	f.OnMatch(goods.Labour, market.Buy, 1, w, wage, 1)
+}
+
+func TestContracts(t *testing.T) {
+	p := employmentParameters(2)
+	f := NewFirm(goods.Grain, 100, 2, 10000)
+	w := NewWorker(100, 2, 0)
+	f.employment = p.Employment
+
+	hire(f, w, 90)
+	if f.Employees() != 1 || w.Unemployed() || w.Money() != 90 {
+		t.Fatalf("after hiring: got %d employees, unemployed %t, money %d", f.Employees(), w.Unemployed(), w.Money())
+	}
+
+	// The worker is paid the contract wage without going back on the market.
+	f.reset()
+	f.targetWorkers = 10
+	w.reset()
+	f.payroll(p, Clock{})
+	if w.Unemployed() || w.Money() != 180 || f.WorkersHired() != 1 {
+		t.Errorf("after payroll: got unemployed %t, money %d, %d workers hired", w.Unemployed(), w.Money(), f.WorkersHired())
+	}
+
+	// Contracts last for two iterations, including the one the worker was hired in.
+	f.reset()
+	w.reset()
+	f.payroll(p, Clock{})
+	if !w.Unemployed() || f.Employees() != 0 || w.Money() != 180 {
+		t.Errorf("after expiry: got unemployed %t, %d employees, money %d", w.Unemployed(), f.Employees(), w.Money())
+	}
+
+	want := EmploymentEvents{Hires: 1, Expiries: 1}
+	if got := p.Employment.Events(); got != want {
+		t.Errorf("got events %+v, want %+v", got, want)
+	}
+}
+
+func TestQuitsAndLayoffs(t *testing.T) {
+	p := employmentParameters(0)
+	f1 := NewFirm(goods.Grain, 100, 2, 10000)
+	f2 := NewFirm(goods.Meat, 100, 2, 10000)
+	f1.employment = p.Employment
+	f2.employment = p.Employment
+	w1 := NewWorker(100, 2, 0)
+	w2 := NewWorker(100, 2, 0)
+
+	hire(f1, w1, 90)
+	hire(f1, w2, 90)
+	// w1 finds a better job.
+	hire(f2, w1, 95)
+	if f1.Employees() != 1 || f2.Employees() != 1 || w1.Unemployed() {
+		t.Errorf("after quitting: got %d and %d employees, unemployed %t", f1.Employees(), f2.Employees(), w1.Unemployed())
+	}
+
+	// f1 doesn't want anyone any more.
+	f1.reset()
+	f1.targetWorkers = 0
+	f1.payroll(p, Clock{})
+	if f1.Employees() != 0 || !w2.Unemployed() {
+		t.Errorf("after layoff: got %d employees, unemployed %t", f1.Employees(), w2.Unemployed())
+	}
+
+	want := EmploymentEvents{Hires: 3, Quits: 1, Layoffs: 1}
+	if got := p.Employment.Events(); got != want {
+		t.Errorf("got events %+v, want %+v", got, want)
+	}
+}
+
+func TestEmployedWorkersOnlySearchForBetterWages(t *testing.T) {
+	p := employmentParameters(0)
+	f := NewFirm(goods.Grain, 100, 2, 10000)
+	f.employment = p.Employment
+	w := NewWorker(100, 2, 0)
+	hire(f, w, 90)
+
+	w.Act(p, Clock{Cycle: 1, Elapsed: 1})
+	p.LabourMarket.Reset()
+	if p.LabourMarket.Ask() != 0 {
+		t.Errorf("employed worker asked for %d with nobody offering more", p.LabourMarket.Ask())
+	}
+
+	// Someone offers more, so the worker goes looking.
+	p.LabourMarket.Post(&market.Order{Price: 120, Size: 1, Side: market.Buy, Owner: NewFirm(goods.Meat, 120, 2, 1000)})
+	p.LabourMarket.Reset()
+	w.Act(p, Clock{Cycle: 2, Elapsed: 2})
+	p.LabourMarket.Reset()
+	if p.LabourMarket.Ask() != 120 {
+		t.Errorf("got ask %d, want the worker to ask for 120", p.LabourMarket.Ask())
+	}
+}
+
+func TestSwitchingJobsPaysOneWage(t *testing.T) {
+	// Sets up a worker under contract at 90 with the first firm, who saw the
+	// second firm offering 120 last time.
+	setup := func() (*Parameters, *Firm, *Firm, *Worker) {
+		p := employmentParameters(0)
+		f1 := NewFirm(goods.Grain, 100, 2, 10000)
+		f2 := NewFirm(goods.Meat, 120, 2, 10000)
+		f1.employment = p.Employment
+		f2.employment = p.Employment
+		w := NewWorker(90, 2, 0)
+		hire(f1, w, 90)
+		f1.money = 10000
+		w.money = 0
+
+		p.LabourMarket.Post(&market.Order{Price: 120, Size: 1, Side: market.Buy, Owner: NewFirm(goods.Meat, 120, 2, 1000)})
+		p.LabourMarket.Reset()
+		f1.targetWorkers = 1
+		f2.targetWorkers = 1
+		return p, f1, f2, w
+	}
+	clock := Clock{Cycle: 1, Phase: LabourPhase, Elapsed: 1}
+
+	// The worker goes looking before being paid, and is paid by the new firm.
+	p, f1, f2, w := setup()
+	w.Act(p, clock)
+	f1.Act(p, clock)
+	f2.Act(p, clock)
+	p.LabourMarket.Reset()
+	if w.Money() != 120 || f2.Employees() != 1 || f1.Employees() != 0 {
+		t.Errorf("looking first: got money %s and %d and %d employees, want 120 with the second firm", w.Money(), f1.Employees(), f2.Employees())
+	}
+	if f1.Money() != 10000 || f1.WorkersHired() != 0 {
+		t.Errorf("looking first: old firm has %s and hired %s, want 10000 and 0", f1.Money(), f1.WorkersHired())
+	}
+
+	// Once paid, the worker stays where they are until next time.
+	p, f1, f2, w = setup()
+	f1.Act(p, clock)
+	w.Act(p, clock)
+	f2.Act(p, clock)
+	p.LabourMarket.Reset()
+	if w.Money() != 90 || f1.Employees() != 1 || f2.Employees() != 0 {
+		t.Errorf("paid first: got money %s and %d and %d employees, want 90 with the first firm", w.Money(), f1.Employees(), f2.Employees())
+	}
+
+	// Looking without finding anything, the worker is paid once the market closes.
+	p, f1, _, w = setup()
+	w.Act(p, clock)
+	f1.Act(p, clock)
+	if w.Money() != 0 {
+		t.Errorf("got paid %s while looking, want nothing until the market closes", w.Money())
+	}
+	p.LabourMarket.Reset()
+	if w.Money() != 90 || f1.Employees() != 1 || f1.WorkersHired() != market.Units(1) {
+		t.Errorf("not found: got money %s, %d employees and %s hired, want 90, 1 and 1", w.Money(), f1.Employees(), f1.WorkersHired())
+	}
+
+	// A firm hiring at what the worker asks for gives them a raise instead.
+	p, f1, _, w = setup()
+	f1.wage = 120
+	w.Act(p, clock)
+	f1.reset()
+	f1.payroll(p, clock)
+	p.LabourMarket.Reset()
+	if w.Money() != 120 || f1.Employees() != 1 || f1.WorkersHired() != market.Units(1) {
+		t.Errorf("raise: got money %s, %d employees and %s hired, want 120, 1 and 1", w.Money(), f1.Employees(), f1.WorkersHired())
+	}
+}
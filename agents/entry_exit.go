@@ -0,0 +1,102 @@
+package agents
+
+import (
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+// EntryExit decides when firms enter and leave each industry, based on how
+// profitable the industry is.
+//
+// The employees of a firm that exits are laid off, and go back on the labour
+// market the next iteration.
+type EntryExit struct {
+	// Average profit per firm in an industry above which a new firm enters.
+	EntryProfit float64
+	// How many iterations in a row a firm can make losses before it exits.
+	ExitAfter int
+	// How much cash a new firm starts out with.
+	EntryMoney market.Price
+	// Wage and price that a new firm starts with if its industry is empty.
+	InitialWage  market.Price
+	InitialPrice market.Price
+
+	lossStreaks map[*Firm]int
+}
+
+// Turnover is the result of one round of entry and exit.
+type Turnover struct {
+	Entered map[goods.Good][]*Firm
+	Exited  map[goods.Good][]*Firm
+}
+
+// Update looks at how the given firms did this iteration and decides which
+// new firms enter and which of the existing ones exit. Exiting firms take
+// their cash with them, and entering firms bring EntryMoney into the economy.
+func (e *EntryExit) Update(p *Parameters, firms []*Firm) Turnover {
+	if e.lossStreaks == nil {
+		e.lossStreaks = map[*Firm]int{}
+	}
+
+	t := Turnover{
+		Entered: map[goods.Good][]*Firm{},
+		Exited:  map[goods.Good][]*Firm{},
+	}
+	profits := map[goods.Good]float64{}
+	incumbents := map[goods.Good][]*Firm{}
+
+	for _, f := range firms {
+		if f.Profit() < 0 {
+			e.lossStreaks[f]++
+		} else {
+			e.lossStreaks[f] = 0
+		}
+
+		// A firm that can't pay a single worker and has nothing left to sell, or to produce
+		// next iteration, is bankrupt.
+		bankrupt := f.money < f.wage && f.inventory == 0 && f.workersHired == 0
+		if bankrupt || e.lossStreaks[f] >= e.ExitAfter {
+			t.Exited[f.goodProduced] = append(t.Exited[f.goodProduced], f)
+			delete(e.lossStreaks, f)
+			if f.employment != nil {
+				f.layOff(0)
+			}
+			continue
+		}
+
+		profits[f.goodProduced] += f.Profit()
+		incumbents[f.goodProduced] = append(incumbents[f.goodProduced], f)
+	}
+
+	for _, good := range goods.AllGoods {
+		if e.shouldEnter(p, good, incumbents[good], profits[good]) {
+			wage, price := e.startingPrices(incumbents[good])
+			t.Entered[good] = append(t.Entered[good], NewFirm(good, wage, price, e.EntryMoney))
+		}
+	}
+
+	return t
+}
+
+func (e *EntryExit) shouldEnter(p *Parameters, good goods.Good, incumbents []*Firm, profits float64) bool {
+	if len(incumbents) == 0 {
+		// Nobody is making this good, so enter if anyone is trying to buy it.
+		return p.Goods[good].Market.Bid() > 0
+	}
+	return profits/float64(len(incumbents)) > e.EntryProfit
+}
+
+// startingPrices gives a new firm the average wage and price of its industry.
+func (e *EntryExit) startingPrices(incumbents []*Firm) (market.Price, market.Price) {
+	if len(incumbents) == 0 {
+		return e.InitialWage, e.InitialPrice
+	}
+
+	wage, price := 0.0, 0.0
+	for _, f := range incumbents {
+		wage += f.wage.Float()
+		price += f.price.Float()
+	}
+	n := float64(len(incumbents))
+	return market.NewPrice(wage / n), market.NewPrice(price / n)
+}
@@ -0,0 +1,43 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+func TestEntryExit(t *testing.T) {
+	p := &Parameters{Goods: map[goods.Good]GoodParameters{}}
+	for _, good := range goods.AllGoods {
+		p.Goods[good] = GoodParameters{Market: market.NewDoubleAuction(good)}
+	}
+	e := &EntryExit{EntryProfit: 50, ExitAfter: 2, EntryMoney: 1000}
+
+	profitable := NewFirm(goods.Grain, 10, 4, 500)
+	profitable.revenue = 100
+	losing := NewFirm(goods.Meat, 10, 2, 500)
+	losing.wageBill = 20
+	bankrupt := NewFirm(goods.Vegetables, 10, 2, 5)
+	firms := []*Firm{profitable, losing, bankrupt}
+
+	turnover := e.Update(p, firms)
+	if got := turnover.Exited[goods.Vegetables]; len(got) != 1 || got[0] != bankrupt {
+		t.Errorf("got %v exiting vegetables, want the bankrupt firm", got)
+	}
+	if got := turnover.Exited[goods.Meat]; len(got) != 0 {
+		t.Errorf("got %v exiting meat after one loss, want none", got)
+	}
+	entered := turnover.Entered[goods.Grain]
+	if len(entered) != 1 {
+		t.Fatalf("got %d grain entrants, want 1", len(entered))
+	}
+	if f := entered[0]; f.Money() != 1000 || f.Wage() != 10 || f.Price() != 4 {
+		t.Errorf("entrant has money %d, wage %d, price %d; want 1000, 10, 4", f.Money(), f.Wage(), f.Price())
+	}
+
+	turnover = e.Update(p, []*Firm{profitable, losing})
+	if got := turnover.Exited[goods.Meat]; len(got) != 1 || got[0] != losing {
+		t.Errorf("got %v exiting meat after two losses, want the losing firm", got)
+	}
+}
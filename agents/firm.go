@@ -23,17 +23,70 @@ type Firm struct {
 	salesMade market.Size
 	// How many sales this firm wanted to make last iteration.
 	targetSales market.Size
+	// How much cash this firm has on hand.
+	money market.Price
+	// How much of its good this firm has in stock.
+	inventory market.Size
+	// How much this firm made from sales this iteration.
+	revenue market.Price
+	// How much this firm paid in wages this iteration.
+	wageBill market.Price
+	// Employment contracts, if they are being used.
+	employment *Employment
+	// Who is under contract to work for this firm.
+	employees []*Worker
+	// How many of this firm's orders the markets have turned down.
+	rejected int
 }
 
 // NewFirm creates a new firm with the given production parameters.
-func NewFirm(goodProduced goods.Good, initialWage, initialPrice market.Price) *Firm {
-	return &Firm{goodProduced, initialWage, initialPrice, 0, 0, 0, 0}
+func NewFirm(goodProduced goods.Good, initialWage, initialPrice, initialMoney market.Price) *Firm {
+	return &Firm{
+		goodProduced: goodProduced,
+		wage:         initialWage,
+		price:        initialPrice,
+		money:        initialMoney,
+	}
 }
 
+// Money gets how much cash this firm has on hand.
+func (f *Firm) Money() market.Price { return f.money }
+
+// Inventory gets how much of its good this firm has in stock.
+func (f *Firm) Inventory() market.Size { return f.inventory }
+
+// Good gets the good that this firm produces.
+func (f *Firm) Good() goods.Good { return f.goodProduced }
+
+// Wage gets the wage this firm is hiring at.
+func (f *Firm) Wage() market.Price { return f.wage }
+
+// Price gets the price this firm is selling its good at.
+func (f *Firm) Price() market.Price { return f.price }
+
+// Revenue gets how much money this firm made from sales this iteration.
+func (f *Firm) Revenue() market.Price { return f.revenue }
+
+// WageBill gets how much this firm paid in wages this iteration.
+func (f *Firm) WageBill() market.Price { return f.wageBill }
+
+// Profit gets how much money this firm made this iteration, i.e. sales minus
+// wages paid.
+func (f *Firm) Profit() float64 { return float64(f.revenue) - float64(f.wageBill) }
+
 // TargetWorkers gets the number of workers that this firm is trying to hire
 // this period.
 func (f *Firm) TargetWorkers() market.Size { return f.targetWorkers }
 
+// WorkersHired gets the number of workers that this firm has hired this period.
+func (f *Firm) WorkersHired() market.Size { return f.workersHired }
+
+// SalesMade gets how much of its good this firm has sold this period.
+func (f *Firm) SalesMade() market.Size { return f.salesMade }
+
+// Rejected gets how many of this firm's orders the markets have turned down.
+func (f *Firm) Rejected() int { return f.rejected }
+
 // TargetSupply gives the amount of a good this firm supplies.
 func (f *Firm) TargetSupply(good goods.Good) market.Size {
 	if good == f.goodProduced {
@@ -51,46 +104,108 @@ func (f *Firm) TargetDemand(good goods.Good) market.Size {
 	return 0
 }
 
-// Act triggers the firm's decision process.
-func (f *Firm) Act(p *Parameters, iteration int) {
-	if iteration > 0 {
-		f.adjustPrices(p)
+// Act triggers the firm's decision process for a phase of the cycle.
+func (f *Firm) Act(p *Parameters, c Clock) {
+	f.employment = p.Employment
+	switch c.Phase {
+	case SinglePhase:
+		// There's nothing to adjust from until agents have been to market once.
+		if !c.First() {
+			f.adjustPrice(p)
+			f.adjustWage(p)
+		}
+		f.produce(p)
+		f.chooseTargets(p)
+		// Reset before placing orders, since fills will update our internal counters.
+		f.reset()
+		if p.Employment != nil {
+			f.payroll(p, c)
+		}
+		f.postVacancies(p)
+		f.postSales(p)
+
+	case LabourPhase:
+		if !c.First() {
+			f.adjustWage(p)
+		}
+		f.chooseWorkers(p)
+		f.resetHiring()
+		if p.Employment != nil {
+			f.payroll(p, c)
+		}
+		f.postVacancies(p)
+
+	case ProductionPhase:
+		f.produce(p)
+
+	case GoodsPhase:
+		f.targetSales = f.inventory
+		f.resetSales()
+		f.postSales(p)
+
+	case ConsumptionPhase:
+		// The goods markets have just closed, so what's left in stock is what
+		// didn't sell.
+		f.adjustPrice(p)
 	}
-	f.chooseTargets(p)
-	// Reset before placing orders, since fills will update our internal counters.
-	f.reset()
-	f.placeOrders(p)
 }
 
-func (f *Firm) adjustPrices(p *Parameters) {
-	// First adjust the price.
-	if f.salesMade < f.targetSales {
-		// Didn't sell enough, hit the bid if possible.
+// adjustPrice lowers the firm's price if it didn't sell everything last time,
+// and raises it if it did.
+func (f *Firm) adjustPrice(p *Parameters) {
+	if f.inventory > 0 {
+		// Didn't sell everything in stock, hit the bid if possible.
 		mkt := p.Goods[f.goodProduced].Market
 		if mkt.Bid() > 0 {
 			f.price = mkt.Bid()
-		} else if f.price-p.Increment > 0 {
+		} else if f.price.Sub(p.Increment) > 0 {
 			f.price -= p.Increment
 		}
 	} else {
-		// Made enough sales, raise prices a little bit.
-		f.price += p.Increment
+		// Sold out, raise prices a little bit.
+		f.price = f.price.Add(p.Increment)
 	}
+}
 
-	// Now adjust the wage.
+// adjustWage raises the firm's wage if it didn't hire everyone it wanted last
+// time, and lowers it if it did.
+func (f *Firm) adjustWage(p *Parameters) {
 	if f.workersHired < f.targetWorkers {
 		// Didn't hire enough people, lift the offer if available.
 		if p.LabourMarket.Ask() > 0 {
 			f.wage = p.LabourMarket.Ask()
 		} else {
-			f.wage += p.Increment
+			f.wage = f.wage.Add(p.Increment)
 		}
-	} else if f.wage-p.Increment > 0 {
+	} else if f.wage.Sub(p.Increment) > 0 {
 		f.wage -= p.Increment
 	}
 }
 
+// produce spoils some of the stock carried over from last iteration, then adds
+// what the workers hired made to it.
+func (f *Firm) produce(p *Parameters) {
+	goodInfo := p.Goods[f.goodProduced]
+
+	spoiled := market.CeilSize(f.inventory.Float() * goodInfo.Spoilage)
+	if spoiled > f.inventory {
+		spoiled = f.inventory
+	}
+	f.inventory -= spoiled
+
+	// Can only produce if we managed to hire workers. In a single pass these were
+	// hired last iteration, which produces a lag between prices and wages.
+	f.inventory = f.inventory.Add(market.NewSize(f.production(p, f.workersHired.Float())))
+}
+
 func (f *Firm) chooseTargets(p *Parameters) {
+	f.chooseWorkers(p)
+	// Try to sell everything in stock.
+	f.targetSales = f.inventory
+}
+
+// chooseWorkers decides how many workers the firm wants to hire.
+func (f *Firm) chooseWorkers(p *Parameters) {
 	goodInfo := p.Goods[f.goodProduced]
 
 	/*
@@ -103,63 +218,105 @@ func (f *Firm) chooseTargets(p *Parameters) {
 		   L = (wage / (price * scale * tech))^(1 / (scale - 1))
 
 	*/
-	base := float64(f.wage) / (float64(f.price) * goodInfo.Tech * goodInfo.Scale)
+	base := f.wage.Float() / (f.price.Float() * goodInfo.Tech * goodInfo.Scale)
 	exp := 1.0 / (goodInfo.Scale - 1.0)
 	targetLabour := math.Pow(base, exp)
 
-	// Since labour is discrete, need to see which of the ceiling or floor gives better profits.
+	// Since workers come in whole units, need to see which of the ceiling or floor gives
+	// better profits.
 	if f.profits(p, math.Ceil(targetLabour)) > f.profits(p, math.Floor(targetLabour)) {
-		f.targetWorkers = market.Size(math.Ceil(targetLabour))
+		f.targetWorkers = market.NewSize(math.Ceil(targetLabour))
 	} else {
-		f.targetWorkers = market.Size(math.Floor(targetLabour))
+		f.targetWorkers = market.NewSize(math.Floor(targetLabour))
 	}
 
-	if f.workersHired > 0 {
-		// Can only produce if we managed to hire workers last iteration.
-		// Note that this will produce a lag between prices and wages.
-		f.targetSales = market.Size(math.Floor(f.production(p, float64(f.workersHired))))
+	// Can't hire more workers than we can pay for.
+	if affordable := f.affordable(); f.targetWorkers > affordable {
+		f.targetWorkers = affordable
 	}
 
 	// If profits at this level are negative, don't produce anything.
 	if f.profits(p, float64(f.targetWorkers)) < 0 {
 		f.targetWorkers = 0
-		f.targetSales = 0
 	}
 }
 
-func (f *Firm) placeOrders(p *Parameters) {
-	if f.targetWorkers > 0 {
-		p.LabourMarket.Post(&market.Order{
+// postVacancies goes to the labour market for the workers the firm still
+// needs.
+func (f *Firm) postVacancies(p *Parameters) {
+	vacancies := f.targetWorkers
+	if p.Employment != nil {
+		// Only need to hire whoever isn't already under contract, and can only hire what's
+		// left after paying them.
+		vacancies = f.targetWorkers.Sub(market.Units(len(f.employees)))
+		if affordable := f.affordable(); vacancies > affordable {
+			vacancies = affordable
+		}
+	}
+
+	if vacancies > 0 {
+		ok := post(p.LabourMarket, &market.Order{
 			Price: f.wage,
-			Size:  f.targetWorkers,
+			Size:  vacancies,
 			Side:  market.Buy,
 			Owner: f,
-		})
+		}, &f.rejected)
+		if !ok {
+			// Nobody can be hired this time around.
+			f.targetWorkers = f.targetWorkers.Sub(vacancies)
+			vacancies = 0
+		}
 	}
 
+	if p.Employment != nil {
+		p.Employment.events.Vacancies += vacancies.Units()
+	}
+}
+
+// postSales puts the firm's stock up for sale.
+func (f *Firm) postSales(p *Parameters) {
 	if f.targetSales > 0 {
 		goodInfo := p.Goods[f.goodProduced]
-		goodInfo.Market.Post(&market.Order{
+		ok := post(goodInfo.Market, &market.Order{
 			Price: f.price,
 			Size:  f.targetSales,
 			Side:  market.Sell,
 			Owner: f,
-		})
+		}, &f.rejected)
+		if !ok {
+			f.targetSales = 0
+		}
 	}
 }
 
+// affordable gets how many whole workers the firm can pay for at its wage,
+// after the wages it is holding back.
+func (f *Firm) affordable() market.Size {
+	return market.Units(f.money.Sub(f.held()).Div(f.wage).Units())
+}
+
 func (f *Firm) reset() {
+	f.resetHiring()
+	f.resetSales()
+}
+
+func (f *Firm) resetHiring() {
 	f.workersHired = 0
+	f.wageBill = 0
+}
+
+func (f *Firm) resetSales() {
 	f.salesMade = 0
+	f.revenue = 0
 }
 
 // profits calculates how much profit a firm makes given a wage and target labour.
 // Note that this is expected profits - it's possible the firm will not sell all the goods it
 // produces.
 func (f *Firm) profits(p *Parameters, labour float64) float64 {
-	wage := float64(f.wage)
-	price := float64(f.price)
-	return price*f.production(p, labour) - float64(wage)*labour
+	wage := f.wage.Float()
+	price := f.price.Float()
+	return price*f.production(p, labour) - wage*labour
 }
 
 // production calculates how much the firm produces with a given amount of labour.
@@ -169,15 +326,20 @@ func (f *Firm) production(p *Parameters, labour float64) float64 {
 }
 
 // OnFill is triggered when the firm makes a sale.
-func (f *Firm) OnFill(good goods.Good, side market.Side, wage market.Price, size market.Size) {
+func (f *Firm) OnFill(good goods.Good, side market.Side, id market.OrderID, price market.Price, size market.Size) {
 	if good == goods.Labour {
-		f.workersHired += size
+		f.workersHired = f.workersHired.Add(size)
+		debit(&f.money, price.Mul(size), "firm hiring")
+		f.wageBill = f.wageBill.Add(price.Mul(size))
 	} else if good == f.goodProduced {
-		f.salesMade += size
+		f.salesMade = f.salesMade.Add(size)
+		f.inventory -= size
+		f.money = f.money.Add(price.Mul(size))
+		f.revenue = f.revenue.Add(price.Mul(size))
 	}
 }
 
 // OnUnfilled is triggered if the firm has unfilled orders at the end of the iteration.
-func (f *Firm) OnUnfilled(goods.Good, market.Side, market.Size) {
+func (f *Firm) OnUnfilled(goods.Good, market.Side, market.OrderID, market.Size) {
 	// No need to actually do anything here.
 }
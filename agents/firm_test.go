@@ -0,0 +1,100 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+func TestInventoryCarriesOver(t *testing.T) {
+	p := &Parameters{
+		Increment:    1,
+		LabourMarket: market.NewDoubleAuction(goods.Labour),
+		Goods: map[goods.Good]GoodParameters{
+			goods.Meat: {Tech: 10, Scale: 0.5, Share: 1, Spoilage: 0.25, Market: market.NewDoubleAuction(goods.Meat)},
+		},
+	}
+	f := NewFirm(goods.Meat, 100, 2, 0)
+
+	// Four workers produce 10 * 4^0.5 = 20 units.
+	f.workersHired = 4
+	f.produce(p)
+	if got, want := f.Inventory(), market.Size(20); got != want {
+		t.Fatalf("after producing: got %d, want %d", got, want)
+	}
+
+	// Selling some of it takes it out of stock.
+	f.OnFill(goods.Meat, market.Sell, 1, 2, 8)
+	if got, want := f.Inventory(), market.Size(12); got != want {
+		t.Fatalf("after selling: got %d, want %d", got, want)
+	}
+
+	// With no more workers, a quarter of the leftover stock spoils.
+	f.workersHired = 0
+	f.produce(p)
+	if got, want := f.Inventory(), market.Size(9); got != want {
+		t.Fatalf("after spoiling: got %d, want %d", got, want)
+	}
+}
+
+func TestFirmAdjustsPricesAfterFirstCycle(t *testing.T) {
+	for _, tc := range []struct {
+		clock   Clock
+		adjusts bool
+	}{
+		{Clock{}, false},
+		{Clock{Cycle: 1, Elapsed: 1}, true},
+		{Clock{Cycle: 7, Elapsed: 7}, true},
+	} {
+		p := &Parameters{
+			Increment:    1,
+			LabourMarket: market.NewDoubleAuction(goods.Labour),
+			Goods: map[goods.Good]GoodParameters{
+				goods.Meat: {Tech: 10, Scale: 0.5, Share: 1, Market: market.NewDoubleAuction(goods.Meat)},
+			},
+		}
+		f := NewFirm(goods.Meat, 100, 2, 1000)
+		f.Act(p, tc.clock)
+
+		// With nothing in stock and nobody it wanted to hire, the firm raises its
+		// price and lowers its wage.
+		price, wage := market.Price(2), market.Price(100)
+		if tc.adjusts {
+			price, wage = 3, 99
+		}
+		if f.Price() != price || f.Wage() != wage {
+			t.Errorf("cycle %d: got price %s and wage %s, want %s and %s", tc.clock.Cycle, f.Price(), f.Wage(), price, wage)
+		}
+	}
+}
+
+func TestFirmCountsRejectedOrders(t *testing.T) {
+	p := &Parameters{
+		LabourMarket: market.NewDoubleAuction(goods.Labour),
+		Goods: map[goods.Good]GoodParameters{
+			goods.Meat: {Market: market.NewDoubleAuction(goods.Meat)},
+		},
+	}
+
+	// Markets turn down orders with no price.
+	f := NewFirm(goods.Meat, 0, 0, 100)
+	f.targetWorkers = 3
+	f.postVacancies(p)
+	if got, want := f.Rejected(), 1; got != want {
+		t.Fatalf("after the vacancies: got %d rejected, want %d", got, want)
+	}
+	if got := f.TargetWorkers(); got != 0 {
+		t.Errorf("got target of %s workers after the vacancies were rejected, want 0", got)
+	}
+
+	f.inventory = 10
+	f.targetSales = 10
+	f.postSales(p)
+	if got, want := f.Rejected(), 2; got != want {
+		t.Errorf("after the sales: got %d rejected, want %d", got, want)
+	}
+	if got := f.TargetSupply(goods.Meat); got != 0 {
+		t.Errorf("got supply %s after the sales were rejected, want 0", got)
+	}
+}
@@ -0,0 +1,23 @@
+package agents
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/robbrit/econerra/market"
+)
+
+// debit takes an amount out of an agent's money. Agents never spend more than
+// they have, so if they do it's a bug: it panics in strict mode, and otherwise
+// is logged and the agent is left with nothing.
+func debit(money *market.Price, amount market.Price, what string) {
+	left, err := money.CheckedSub(amount)
+	if err != nil {
+		msg := fmt.Sprintf("agents: %s cost %s with only %s on hand", what, amount, *money)
+		if market.Strict {
+			panic(msg)
+		}
+		log.Print(msg)
+	}
+	*money = left
+}
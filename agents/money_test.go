@@ -0,0 +1,127 @@
+package agents
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+func TestFillsMoveMoney(t *testing.T) {
+	f := NewFirm(goods.Grain, 10, 2, 100)
+	w := NewWorker(10, 2, 5)
+
+	// Worker is hired for a wage of 12.
+	f.OnFill(goods.Labour, market.Buy, 1, 12, 1)
+	w.OnFill(goods.Labour, market.Sell, 2, 12, 1)
+	// Worker buys 3 grain for 2 each.
+	f.OnFill(goods.Grain, market.Sell, 1, 2, 3)
+	w.OnFill(goods.Grain, market.Buy, 2, 2, 3)
+
+	if got, want := f.Money(), market.Price(100-12+6); got != want {
+		t.Errorf("firm money: got %d, want %d", got, want)
+	}
+	if got, want := w.Money(), market.Price(5+12-6); got != want {
+		t.Errorf("worker money: got %d, want %d", got, want)
+	}
+}
+
+func TestFirmOnlyHiresWhatItCanAfford(t *testing.T) {
+	p := &Parameters{
+		Increment:    1,
+		LabourMarket: market.NewDoubleAuction(goods.Labour),
+		Goods: map[goods.Good]GoodParameters{
+			goods.Grain: {Tech: 1000, Scale: 0.5, Share: 1, Market: market.NewDoubleAuction(goods.Grain)},
+		},
+	}
+	// Unconstrained, this firm would hire 100 workers.
+	f := NewFirm(goods.Grain, 100, 2, 250)
+	f.Act(p, Clock{})
+	if got, want := f.TargetWorkers(), market.Size(2); got != want {
+		t.Errorf("got %d target workers, want %d", got, want)
+	}
+}
+
+func TestWorkerDemandStaysInBudget(t *testing.T) {
+	p := &Parameters{
+		Increment:     1,
+		Elasticity:    0.8,
+		DissavingRate: 0.5,
+		LabourMarket:  market.NewDoubleAuction(goods.Labour),
+		Goods:         map[goods.Good]GoodParameters{},
+	}
+	for _, good := range goods.AllGoods {
+		p.Goods[good] = GoodParameters{Tech: 1, Scale: 0.5, Share: 1, Market: market.NewDoubleAuction(good)}
+	}
+
+	// An unemployed worker with savings spends some of them.
+	w := NewWorker(100, 3, 60)
+	w.Act(p, Clock{})
+	spent := market.Price(0)
+	for _, good := range goods.AllGoods {
+		spent += market.Price(w.TargetDemand(good)) * 3
+	}
+	if spent == 0 || spent > 30 {
+		t.Errorf("unemployed worker plans to spend %d, want between 1 and 30", spent)
+	}
+}
+
+func TestWorkerDemandSpendsBudget(t *testing.T) {
+	// Shares below 1 and elasticities above 1 are where getting the demand
+	// functions wrong shows up as spending more than the budget.
+	shares := map[goods.Good]float64{goods.Grain: 0.2, goods.Vegetables: 0.5, goods.Meat: 3}
+	prices := map[goods.Good]market.Price{goods.Grain: 3, goods.Vegetables: 7, goods.Meat: 2}
+
+	for _, elasticity := range []float64{0.5, 0.8, 1.2, 3} {
+		p := &Parameters{
+			Elasticity:    elasticity,
+			DissavingRate: 1,
+			Goods:         map[goods.Good]GoodParameters{},
+		}
+		for _, good := range goods.AllGoods {
+			p.Goods[good] = GoodParameters{Share: shares[good]}
+		}
+
+		w := NewWorker(1, 1, 1000)
+		for _, good := range goods.AllGoods {
+			w.prices[good] = prices[good]
+		}
+		w.chooseTargets(p)
+
+		// Demand is rounded down to whole units, which can leave up to a unit
+		// of each good unbought.
+		spent := market.Price(0)
+		for _, good := range goods.AllGoods {
+			spent += market.Price(w.TargetDemand(good)) * prices[good]
+		}
+		if spent > 1000 || spent < 1000-12 {
+			t.Errorf("elasticity %g: demand costs %d, want all of the budget of 1000", elasticity, spent)
+		}
+	}
+}
+
+func TestOverdrawing(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	// Buying more than the worker can pay for is a bug, which leaves them with
+	// nothing rather than wrapping around.
+	w := NewWorker(10, 2, 5)
+	w.OnFill(goods.Grain, market.Buy, 1, 2, 3)
+	if w.Money() != 0 {
+		t.Errorf("got %s after overdrawing, want 0", w.Money())
+	}
+
+	market.Strict = true
+	defer func() { market.Strict = false }()
+	defer func() {
+		if recover() == nil {
+			t.Error("overdrawing didn't panic in strict mode")
+		}
+	}()
+	f := NewFirm(goods.Grain, 10, 2, 5)
+	f.OnFill(goods.Labour, market.Buy, 1, 10, 1)
+}
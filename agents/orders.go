@@ -0,0 +1,13 @@
+package agents
+
+import "github.com/robbrit/econerra/market"
+
+// post sends an order to a market and says whether the market took it. If it
+// didn't, the agent's count of rejected orders goes up by one.
+func post(m market.Market, o *market.Order, rejected *int) bool {
+	if _, err := m.Post(o); err != nil {
+		*rejected++
+		return false
+	}
+	return true
+}
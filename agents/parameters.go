@@ -13,6 +13,8 @@ type GoodParameters struct {
 	Scale float64
 	// CES utility share factor.
 	Share float64
+	// What fraction of unsold stock goes bad each iteration.
+	Spoilage float64
 	// Where agents can buy this good.
 	Market market.Market
 }
@@ -25,6 +27,11 @@ type Parameters struct {
 	LabourMarket market.Market
 	// CES elasticity of substition parameter
 	Elasticity float64
+	// What fraction of their savings an unemployed worker spends each iteration.
+	DissavingRate float64
+	// Employment contracts between firms and workers. If nil, workers are hired fresh every
+	// iteration.
+	Employment *Employment
 
 	Goods map[goods.Good]GoodParameters
 }
@@ -17,13 +17,22 @@ type Worker struct {
 	prices        map[goods.Good]market.Price
 	demand        map[goods.Good]market.Size
 	purchasesMade map[goods.Good]market.Size
+	money         market.Price
+	// The worker's current employment contract, if employment contracts are being used
+	// and the worker has a job.
+	contract *contract
+	// The order looking for a better job than the contract, while it's open.
+	search *market.Order
+	// How many of this worker's orders the markets have turned down.
+	rejected int
 }
 
 // NewWorker creates a new worker.
-func NewWorker(initialWage, initialPrice market.Price) *Worker {
+func NewWorker(initialWage, initialPrice, initialMoney market.Price) *Worker {
 	w := &Worker{
 		unemployed:    true,
 		wage:          initialWage,
+		money:         initialMoney,
 		prices:        map[goods.Good]market.Price{},
 		demand:        map[goods.Good]market.Size{},
 		purchasesMade: map[goods.Good]market.Size{},
@@ -36,22 +45,60 @@ func NewWorker(initialWage, initialPrice market.Price) *Worker {
 	return w
 }
 
-// Act triggers the worker's decision process.
-func (w *Worker) Act(p *Parameters, iteration int) {
-	if iteration > 0 {
+// Money gets how much cash this worker has on hand.
+func (w *Worker) Money() market.Price { return w.money }
+
+// Wage gets the wage this worker is asking for.
+func (w *Worker) Wage() market.Price { return w.wage }
+
+// Unemployed gets whether this worker failed to find work this iteration.
+func (w *Worker) Unemployed() bool { return w.unemployed }
+
+// Purchases gets how much of a good this worker has bought this iteration.
+func (w *Worker) Purchases(good goods.Good) market.Size { return w.purchasesMade[good] }
+
+// Rejected gets how many of this worker's orders the markets have turned down.
+func (w *Worker) Rejected() int { return w.rejected }
+
+// Act triggers the worker's decision process for a phase of the cycle.
+func (w *Worker) Act(p *Parameters, c Clock) {
+	switch c.Phase {
+	case SinglePhase:
+		// There's nothing to adjust from until agents have been to market once.
+		if !c.First() {
+			w.adjustWage(p)
+			w.adjustPrices(p)
+		}
+		w.chooseTargets(p)
+		// Reset before placing orders, since fills will update our internal counters.
+		w.reset()
+		w.postLabour(p, c)
+		w.postBids(p)
+
+	case LabourPhase:
+		if !c.First() {
+			w.adjustWage(p)
+		}
+		w.resetEmployment()
+		w.postLabour(p, c)
+
+	case GoodsPhase:
+		// The labour market has closed, so the budget is based on whether the
+		// worker was hired this cycle.
+		w.chooseTargets(p)
+		w.resetPurchases()
+		w.postBids(p)
+
+	case ConsumptionPhase:
 		w.adjustPrices(p)
 	}
-	w.chooseTargets(p)
-	// Reset before placing orders, since fills will update our internal counters.
-	w.reset()
-	w.placeOrders(p)
 }
 
 // TargetSupply gives the amount of a good this worker supplies.
 // Workers only supply labour, they don't supply any other good.
 func (w *Worker) TargetSupply(good goods.Good) market.Size {
 	if good == goods.Labour {
-		return 1
+		return market.Units(1)
 	}
 	return 0
 }
@@ -64,19 +111,28 @@ func (w *Worker) TargetDemand(good goods.Good) market.Size {
 	return w.demand[good]
 }
 
-func (w *Worker) adjustPrices(p *Parameters) {
-	if w.unemployed {
+// adjustWage sets the wage the worker asks for, based on whether they found
+// work last time.
+func (w *Worker) adjustWage(p *Parameters) {
+	if w.contract != nil {
+		// Under contract, so my wage is whatever was agreed on.
+		w.wage = w.contract.wage
+	} else if w.unemployed {
 		// I was unemployed last round, hit the bid if it's available.
 		if p.LabourMarket.Bid() > 0 {
 			w.wage = p.LabourMarket.Bid()
-		} else if w.wage-p.Increment > 0 {
+		} else if w.wage.Sub(p.Increment) > 0 {
 			w.wage -= p.Increment
 		}
 	} else if p.LabourMarket.Bid() > 0 {
 		// I was employed, bump up my wage if there were still people looking for workers.
-		w.wage += p.Increment
+		w.wage = w.wage.Add(p.Increment)
 	}
+}
 
+// adjustPrices sets the prices the worker expects to pay for goods, based on
+// whether they got everything they wanted last time.
+func (w *Worker) adjustPrices(p *Parameters) {
 	for _, good := range goods.AllGoods {
 		amountBought := w.purchasesMade[good]
 		demand := w.demand[good]
@@ -87,11 +143,11 @@ func (w *Worker) adjustPrices(p *Parameters) {
 			if mkt.Ask() > 0 {
 				w.prices[good] = mkt.Ask()
 			} else {
-				w.prices[good] += p.Increment
+				w.prices[good] = w.prices[good].Add(p.Increment)
 			}
 		} else {
 			// Got enough last time, lower my price expectation.
-			if w.prices[good]-p.Increment > 0 {
+			if w.prices[good].Sub(p.Increment) > 0 {
 				w.prices[good] -= p.Increment
 			}
 		}
@@ -99,8 +155,17 @@ func (w *Worker) adjustPrices(p *Parameters) {
 }
 
 func (w *Worker) chooseTargets(p *Parameters) {
-	if w.unemployed {
-		// If we didn't work, we can't buy things.
+	// Employed workers spend their wage and save whatever is left over, unemployed workers
+	// dissolve some of their savings.
+	budget := market.NewPrice(w.money.Float() * p.DissavingRate)
+	if !w.unemployed {
+		budget = w.wage
+	}
+	if budget > w.money {
+		budget = w.money
+	}
+	if budget == 0 {
+		// If we have no money, we can't buy things.
 		for _, good := range goods.AllGoods {
 			w.demand[good] = 0
 		}
@@ -125,61 +190,102 @@ func (w *Worker) chooseTargets(p *Parameters) {
 	In code:
 		sigma is p.Elasticity
 		beta_i is p.Goods[i].Share
-		m is w.wage
+		m is the budget
 		p_i is w.prices[i]
 	*/
 
 	denominator := 0.0
 	for _, good := range goods.AllGoods {
-		denominator += p.Goods[good].Share * math.Pow(float64(w.prices[good]), 1.0-p.Elasticity)
+		denominator += math.Pow(p.Goods[good].Share, p.Elasticity) * math.Pow(w.prices[good].Float(), 1.0-p.Elasticity)
 	}
 	for _, good := range goods.AllGoods {
-		numerator := math.Pow(p.Goods[good].Share/float64(w.prices[good]), p.Elasticity)
-		demand := numerator * float64(w.wage) / denominator
-		w.demand[good] = market.Size(math.Floor(demand))
+		numerator := math.Pow(p.Goods[good].Share/w.prices[good].Float(), p.Elasticity)
+		demand := numerator * budget.Float() / denominator
+		w.demand[good] = market.NewSize(demand)
 	}
 }
 
-func (w *Worker) placeOrders(p *Parameters) {
-	// Workers will always work.
-	p.LabourMarket.Post(&market.Order{
-		Price: w.wage,
-		Size:  1,
-		Side:  market.Sell,
-		Owner: w,
-	})
+// postLabour goes to the labour market, if the worker needs a job or might
+// find a better one.
+func (w *Worker) postLabour(p *Parameters, c Clock) {
+	if w.contract == nil {
+		// Workers will always work.
+		post(p.LabourMarket, &market.Order{
+			Price: w.wage,
+			Size:  market.Units(1),
+			Side:  market.Sell,
+			Owner: w,
+		}, &w.rejected)
+	} else if bid := p.LabourMarket.Bid(); bid > w.contract.wage && w.contract.paid != c.Cycle {
+		// Already have a job, but someone was offering more last time so look for a better one.
+		// Set before posting, since the order can be filled straight away.
+		w.search = &market.Order{
+			Price: bid,
+			Size:  market.Units(1),
+			Side:  market.Sell,
+			Owner: w,
+		}
+		if !post(p.LabourMarket, w.search, &w.rejected) {
+			w.search = nil
+		}
+	}
+}
 
+// postBids goes to the goods markets for what the worker demands.
+func (w *Worker) postBids(p *Parameters) {
 	for _, good := range goods.AllGoods {
 		if w.demand[good] == 0 {
 			continue
 		}
 
-		p.Goods[good].Market.Post(&market.Order{
+		ok := post(p.Goods[good].Market, &market.Order{
 			Price: w.prices[good],
 			Size:  w.demand[good],
 			Side:  market.Buy,
 			Owner: w,
-		})
+		}, &w.rejected)
+		if !ok {
+			// Nothing is on offer, so nothing will come of it.
+			w.demand[good] = 0
+		}
 	}
 }
 
 func (w *Worker) reset() {
-	w.unemployed = true
+	w.resetEmployment()
+	w.resetPurchases()
+}
+
+func (w *Worker) resetEmployment() {
+	// Workers under contract stay employed unless their employer lets them go.
+	w.unemployed = w.contract == nil
+}
+
+func (w *Worker) resetPurchases() {
 	for _, good := range goods.AllGoods {
 		w.purchasesMade[good] = 0
 	}
 }
 
 // OnFill is triggered when the worker is hired.
-func (w *Worker) OnFill(good goods.Good, side market.Side, wage market.Price, size market.Size) {
+func (w *Worker) OnFill(good goods.Good, side market.Side, id market.OrderID, price market.Price, size market.Size) {
 	if good == goods.Labour {
 		w.unemployed = false
+		w.money = w.money.Add(price.Mul(size))
 	} else {
-		w.purchasesMade[good] += size
+		w.purchasesMade[good] = w.purchasesMade[good].Add(size)
+		debit(&w.money, price.Mul(size), "worker buying "+good.String())
 	}
 }
 
 // OnUnfilled is triggered at the end of the cycle if the worker was not hired.
-func (w *Worker) OnUnfilled(good goods.Good, side market.Side, size market.Size) {
-	// Nothing needed here.
+func (w *Worker) OnUnfilled(good goods.Good, side market.Side, id market.OrderID, size market.Size) {
+	if good != goods.Labour || w.search == nil || w.search.ID != id {
+		return
+	}
+	// Didn't find a better job, so the employer pays the wage it held back.
+	w.search = nil
+	if w.contract != nil {
+		w.contract.employer.pay(w)
+	}
 }
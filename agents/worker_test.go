@@ -0,0 +1,107 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+func TestSmallBudgetDemand(t *testing.T) {
+	p := &Parameters{
+		Elasticity:    0.8,
+		DissavingRate: 1,
+		Goods:         map[goods.Good]GoodParameters{},
+	}
+	for _, good := range goods.AllGoods {
+		p.Goods[good] = GoodParameters{Share: 1}
+	}
+
+	// Whole units only: a budget of 1 can't buy anything at a price of 2.
+	w := NewWorker(1, 2, 1)
+	w.chooseTargets(p)
+	for _, good := range goods.AllGoods {
+		if got := w.TargetDemand(good); got != 0 {
+			t.Errorf("%s: got demand %s, want 0 with whole units", good, got)
+		}
+	}
+
+	if err := market.SetPrecision(2); err != nil {
+		t.Fatal(err)
+	}
+	defer market.SetPrecision(0)
+
+	// With two decimal places, the budget is split evenly: 1/3 spent on each good
+	// at 2 each buys 0.16 of each.
+	w = NewWorker(market.NewPrice(1), market.NewPrice(2), market.NewPrice(1))
+	w.chooseTargets(p)
+	for _, good := range goods.AllGoods {
+		if got, want := w.TargetDemand(good), market.NewSize(0.16); got != want {
+			t.Errorf("%s: got demand %s, want %s", good, got, want)
+		}
+	}
+}
+
+func TestWorkerAdjustsPricesAfterFirstCycle(t *testing.T) {
+	for _, tc := range []struct {
+		clock   Clock
+		adjusts bool
+	}{
+		{Clock{}, false},
+		{Clock{Cycle: 1, Elapsed: 1}, true},
+		{Clock{Cycle: 7, Elapsed: 7}, true},
+	} {
+		p := &Parameters{
+			Increment:     1,
+			Elasticity:    0.8,
+			DissavingRate: 0.5,
+			LabourMarket:  market.NewDoubleAuction(goods.Labour),
+			Goods:         map[goods.Good]GoodParameters{},
+		}
+		for _, good := range goods.AllGoods {
+			p.Goods[good] = GoodParameters{Tech: 1, Scale: 0.5, Share: 1, Market: market.NewDoubleAuction(good)}
+		}
+		w := NewWorker(100, 3, 60)
+		w.Act(p, tc.clock)
+
+		// Unemployed with nobody hiring, and having wanted nothing, the worker
+		// lowers its wage and what it expects to pay.
+		wage, price := market.Price(100), market.Price(3)
+		if tc.adjusts {
+			wage, price = 99, 2
+		}
+		if w.Wage() != wage {
+			t.Errorf("cycle %d: got wage %s, want %s", tc.clock.Cycle, w.Wage(), wage)
+		}
+		for _, good := range goods.AllGoods {
+			if w.prices[good] != price {
+				t.Errorf("cycle %d: got price %s for %s, want %s", tc.clock.Cycle, w.prices[good], good, price)
+			}
+		}
+	}
+}
+
+func TestWorkerCountsRejectedOrders(t *testing.T) {
+	p := &Parameters{
+		LabourMarket: market.NewDoubleAuction(goods.Labour),
+		Goods: map[goods.Good]GoodParameters{
+			goods.Meat: {Share: 1, Market: market.NewDoubleAuction(goods.Meat)},
+		},
+	}
+
+	// Markets turn down orders with no price.
+	w := NewWorker(0, 0, 100)
+	w.postLabour(p, Clock{})
+	if got, want := w.Rejected(), 1; got != want {
+		t.Fatalf("after the labour offer: got %d rejected, want %d", got, want)
+	}
+
+	w.demand[goods.Meat] = 5
+	w.postBids(p)
+	if got, want := w.Rejected(), 2; got != want {
+		t.Errorf("after the bid: got %d rejected, want %d", got, want)
+	}
+	if got := w.TargetDemand(goods.Meat); got != 0 {
+		t.Errorf("got demand %s after the bid was rejected, want 0", got)
+	}
+}
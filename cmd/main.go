@@ -1,137 +1,338 @@
 package main
 
 import (
-	"encoding/csv"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
-	"math/rand"
 	"os"
+	"strings"
+	"text/tabwriter"
 
-	"github.com/robbrit/econerra/agents"
+	"github.com/robbrit/econerra/experiment"
 	"github.com/robbrit/econerra/goods"
 	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/output"
+	"github.com/robbrit/econerra/scenario"
+	"github.com/robbrit/econerra/sim"
 )
 
-const (
-	numWorkers = 1000
-	numCycles  = 100
-	initWage   = 100
-	initPrice  = 2
-	increment  = 1
-	randSeed   = 123456
-	elasticity = 0.8
-	// All goods will use the same scale.
-	scale = 0.5
-)
+// outputFlag collects the -output flags, since there can be more than one.
+type outputFlag []output.Spec
 
-var (
-	numFirms = map[goods.Good]int{
-		goods.Grain:      5,
-		goods.Vegetables: 5,
-		goods.Meat:       15,
+func (o *outputFlag) String() string {
+	var specs []string
+	for _, s := range *o {
+		specs = append(specs, s.String())
 	}
-	technology = map[goods.Good]float64{
-		goods.Grain:      1000.0,
-		goods.Vegetables: 800.0,
-		goods.Meat:       500.0,
-	}
-	shares = map[goods.Good]float64{
-		goods.Grain:      2.0,
-		goods.Vegetables: 1.0,
-		goods.Meat:       5.0,
+	return strings.Join(specs, ",")
+}
+
+func (o *outputFlag) Set(value string) error {
+	spec, err := output.ParseSpec(value)
+	if err != nil {
+		return err
 	}
+	*o = append(*o, spec)
+	return nil
+}
+
+var (
+	scenarioPath = flag.String("scenario", "", "Path to a JSON scenario file. Uses the default scenario if empty.")
+	outputs      outputFlag
+
+	workersPanel = flag.String("workers-panel", "", "Path to write a CSV of every worker's state each cycle to.")
+	firmsPanel   = flag.String("firms-panel", "", "Path to write a CSV of every firm's state each cycle to.")
+	panelSample  = flag.Float64("panel-sample", 1, "Fraction of agents to include in the panels.")
+	panelEvery   = flag.Int("panel-every", 1, "Only write the panels every this many cycles.")
+	panelGoods   = flag.String("panel-goods", "", "Comma-separated goods to include firms for in the firms panel. Includes all if empty.")
+
+	runs        = flag.Int("runs", 1, "Number of replications to run with different seeds. More than one writes a summary instead of the usual output.")
+	summaryPath = flag.String("summary", "summary.csv", "Path to write the summary of the replications to, when -runs is more than one.")
+
+	sweepPath   = flag.String("sweep", "", "Path to a JSON sweep file. If given, runs the sweep instead of a single simulation.")
+	sweepOutput = flag.String("sweep-output", "sweep.csv", "Path to write the results of a sweep to.")
+
+	calibrationPath   = flag.String("calibrate", "", "Path to a JSON calibration file. If given, searches for the parameters that fit its targets instead of running a single simulation.")
+	calibrationOutput = flag.String("calibrate-output", "calibration.json", "Path to write the result of a calibration to.")
+
+	checkpointPath = flag.String("checkpoint", "", "Path to save the state of the simulation to once it has run, so that it can be picked up again with -restore.")
+	restorePath    = flag.String("restore", "", "Path to a checkpoint to pick a simulation up from, instead of starting a scenario.")
+	cycles         = flag.Int("cycles", 0, "Number of cycles to run. Defaults to the rest of the scenario.")
+
+	diffPaths    = flag.String("diff", "", "Two CSV outputs to compare series by series, separated by a comma, e.g. a.csv,b.csv. Compares their macroeconomic statistics too if they were written.")
+	relTolerance = flag.Float64("tolerance", 0, "Relative difference up to which -diff counts two values as the same.")
+	absTolerance = flag.Float64("abs-tolerance", 0, "Absolute difference up to which -diff counts two values as the same.")
+
+	strict = flag.Bool("strict", false, "Check every market's order book after every order, and stop if anything is wrong. Slow.")
 )
 
-type actor interface {
-	Act(*agents.Parameters, int)
-	TargetDemand(goods.Good) market.Size
-	TargetSupply(goods.Good) market.Size
+func init() {
+	flag.Var(&outputs, "output", "Where to write results, as format:path. Can be repeated. Formats are "+
+		strings.Join(output.Formats, ", ")+". Defaults to csv:output.csv.")
 }
 
-func main() {
-	log.Printf("Starting simulation...\n")
+func openPanel(s *sim.Simulation, seed int64) (*output.Panel, error) {
+	opts := output.PanelOptions{
+		SampleRate: *panelSample,
+		Every:      *panelEvery,
+		Seed:       seed,
+	}
+	if *panelGoods != "" {
+		for _, name := range strings.Split(*panelGoods, ",") {
+			good, err := goods.Parse(name)
+			if err != nil {
+				return nil, err
+			}
+			opts.Goods = append(opts.Goods, good)
+		}
+	}
 
-	markets := []market.Market{}
+	var workers, firms io.Writer
+	if *workersPanel != "" {
+		f, err := os.Create(*workersPanel)
+		if err != nil {
+			return nil, err
+		}
+		workers = f
+	}
+	if *firmsPanel != "" {
+		f, err := os.Create(*firmsPanel)
+		if err != nil {
+			return nil, err
+		}
+		firms = f
+	}
+	return output.NewPanel(s, workers, firms, opts)
+}
 
-	params := agents.Parameters{
-		Increment:    increment,
-		LabourMarket: market.NewDoubleAuction(goods.Labour),
-		Goods:        map[goods.Good]agents.GoodParameters{},
+// runMonteCarlo runs replications of a scenario in parallel and writes the
+// summary of them.
+func runMonteCarlo(scen *scenario.Scenario) {
+	mc := &experiment.MonteCarlo{Scenario: scen, Runs: *runs}
+	summary, err := mc.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	writeFile(*summaryPath, summary.WriteCSV)
+}
+
+// runSweep runs a sweep of a scenario and writes its results.
+func runSweep(scen *scenario.Scenario) {
+	sw, err := experiment.LoadSweep(*sweepPath, scen)
+	if err != nil {
+		log.Fatalf("Unable to load sweep %s: %s", *sweepPath, err)
+	}
+	table, err := sw.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeFile(*sweepOutput, table.WriteCSV)
+}
+
+// runCalibration calibrates a scenario, logs how well it fits and writes the
+// result.
+func runCalibration(scen *scenario.Scenario) {
+	c, err := experiment.LoadCalibration(*calibrationPath, scen)
+	if err != nil {
+		log.Fatalf("Unable to load calibration %s: %s", *calibrationPath, err)
+	}
+	fit, err := c.Run()
+	if err != nil {
+		log.Fatal(err)
 	}
-	markets = append(markets, params.LabourMarket)
 
-	for _, good := range goods.AllGoods {
-		params.Goods[good] = agents.GoodParameters{
-			Tech:   technology[good],
-			Scale:  scale,
-			Share:  shares[good],
-			Market: market.NewDoubleAuction(good),
+	log.Printf("Distance %g after %d evaluations (converged: %t)", fit.Distance, fit.Evaluations, fit.Converged)
+	for i, name := range fit.Params {
+		log.Printf("  %s = %g", name, fit.Values[i])
+	}
+	for _, m := range fit.Moments {
+		stat := m.Stat
+		if stat == "" {
+			stat = "mean"
 		}
-		markets = append(markets, params.Goods[good].Market)
+		log.Printf("  %s of %s: %g (+/- %g), target %g", stat, m.Series, m.Simulated, m.StdErr, m.Value)
 	}
 
-	var actors []actor
-	for _, good := range goods.AllGoods {
-		for i := 0; i < numFirms[good]; i++ {
-			actors = append(actors, agents.NewFirm(good, initWage, initPrice))
+	data, err := json.MarshalIndent(fit, "", "\t")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*calibrationOutput, append(data, '\n'), 0644); err != nil {
+		log.Fatalf("Unable to write %s: %s", *calibrationOutput, err)
+	}
+}
+
+// runDiff compares two runs' outputs, listing the series that differ. It
+// exits with a failure if there are any.
+func runDiff() {
+	paths := strings.Split(*diffPaths, ",")
+	if len(paths) != 2 {
+		log.Fatalf("-diff needs two paths separated by a comma, got %q", *diffPaths)
+	}
+	var runs []*experiment.Run
+	for _, path := range paths {
+		run, err := readRun(path)
+		if err != nil {
+			log.Fatalf("Unable to read %s: %s", path, err)
 		}
+		runs = append(runs, run)
 	}
-	for i := 0; i < numWorkers; i++ {
-		actors = append(actors, agents.NewWorker(initWage, initPrice))
+
+	diffs, err := experiment.Diff(runs[0], runs[1], experiment.Tolerance{Absolute: *absTolerance, Relative: *relTolerance})
+	if err != nil {
+		log.Fatalf("Unable to compare %s and %s: %s", paths[0], paths[1], err)
+	}
+	if len(diffs) == 0 {
+		log.Printf("No differences in %d series over %d cycles", len(runs[0].Series), len(runs[0].Values))
+		return
 	}
 
-	r := rand.New(rand.NewSource(randSeed))
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Series\tCycles\tFirst\t%s\t%s\tLargest\n", paths[0], paths[1])
+	for _, d := range diffs {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%g\t%g\t%g\n", d.Series, d.Cycles, d.Cycle, d.A, d.B, d.Largest)
+	}
+	w.Flush()
+	os.Exit(1)
+}
 
-	filename := "output.csv"
-	f, err := os.Create(filename)
+// readRun reads a run's CSV output, along with its macroeconomic statistics
+// if they're next to it.
+func readRun(path string) (*experiment.Run, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		log.Fatalf("Unable to open CSV file %s for writing: %s", filename, err)
+		return nil, err
 	}
 	defer f.Close()
-	w := csv.NewWriter(f)
-
-	w.Write([]string{
-		"Iteration",
-		"Good",
-		"Bid",
-		"Ask",
-		"Low",
-		"High",
-		"Volume",
-		"Supply",
-		"Demand",
-	})
-
-	for i := 0; i < numCycles; i++ {
-		p := r.Perm(len(actors))
-		for _, i := range p {
-			actors[i].Act(&params, i)
+
+	var macro io.Reader
+	if m, err := os.Open(output.MacroPath(path)); err == nil {
+		defer m.Close()
+		macro = m
+	}
+	return experiment.ReadCSV(f, macro)
+}
+
+// writeFile creates a file and writes to it.
+func writeFile(path string, write func(io.Writer) error) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("Unable to open %s: %s", path, err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		log.Fatalf("Unable to write %s: %s", path, err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Unable to close %s: %s", path, err)
+	}
+}
+
+func main() {
+	flag.Parse()
+	if *diffPaths != "" {
+		runDiff()
+		return
+	}
+	log.Printf("Starting simulation...\n")
+	market.Strict = *strict
+
+	if *restorePath != "" {
+		if *scenarioPath != "" || *calibrationPath != "" || *sweepPath != "" || *runs > 1 {
+			log.Fatalf("-restore picks up a single simulation, and can't be used with -scenario, -calibrate, -sweep or -runs")
 		}
-		for _, mkt := range markets {
-			mkt.Reset()
-
-			supply := market.Size(0)
-			demand := market.Size(0)
-			for _, a := range actors {
-				supply += a.TargetSupply(mkt.Good())
-				demand += a.TargetDemand(mkt.Good())
-			}
+		s, err := restore(*restorePath)
+		if err != nil {
+			log.Fatalf("Unable to restore checkpoint %s: %s", *restorePath, err)
+		}
+		runSimulation(s)
+		return
+	}
 
-			w.Write([]string{
-				fmt.Sprintf("%d", i),
-				fmt.Sprintf("%s", mkt.Good()),
-				fmt.Sprintf("%d", mkt.Bid()),
-				fmt.Sprintf("%d", mkt.Ask()),
-				fmt.Sprintf("%d", mkt.Low()),
-				fmt.Sprintf("%d", mkt.High()),
-				fmt.Sprintf("%d", mkt.Volume()),
-				fmt.Sprintf("%d", supply),
-				fmt.Sprintf("%d", demand),
-			})
+	scen := scenario.Default()
+	if *scenarioPath != "" {
+		var err error
+		if scen, err = scenario.Load(*scenarioPath); err != nil {
+			log.Fatalf("Unable to load scenario %s: %s", *scenarioPath, err)
 		}
 	}
-	w.Flush()
-	if err := w.Error(); err != nil {
+
+	if *calibrationPath != "" {
+		runCalibration(scen)
+		return
+	}
+	if *sweepPath != "" {
+		runSweep(scen)
+		return
+	}
+	if *runs > 1 {
+		runMonteCarlo(scen)
+		return
+	}
+
+	s, err := sim.New(scen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	runSimulation(s)
+}
+
+// runSimulation runs a single simulation, writing its results to the outputs
+// and saving a checkpoint at the end if asked to.
+func runSimulation(s *sim.Simulation) {
+	scen := s.Scenario()
+	if len(outputs) == 0 {
+		outputs = outputFlag{{Format: "csv", Path: "output.csv"}}
+	}
+
+	var sinks []output.Sink
+	for _, spec := range outputs {
+		sink, err := output.Open(spec.Format, spec.Path)
+		if err != nil {
+			log.Fatalf("Unable to open output %s: %s", spec, err)
+		}
+		sinks = append(sinks, sink)
+		s.AddObserver(sink)
+	}
+
+	if *workersPanel != "" || *firmsPanel != "" {
+		panel, err := openPanel(s, scen.Seed)
+		if err != nil {
+			log.Fatalf("Unable to open panel: %s", err)
+		}
+		sinks = append(sinks, panel)
+		s.AddObserver(panel)
+	}
+
+	n := *cycles
+	if n <= 0 {
+		n = scen.Cycles - s.Cycle()
+	}
+	_, err := s.Run(n)
+	for _, sink := range sinks {
+		if cerr := sink.Close(); cerr != nil {
+			log.Printf("Unable to close output: %s", cerr)
+		}
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
+
+	if *checkpointPath != "" {
+		writeFile(*checkpointPath, s.Save)
+	}
+}
+
+// restore picks up a simulation from a checkpoint file.
+func restore(path string) (*sim.Simulation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return sim.Load(f)
 }
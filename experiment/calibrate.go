@@ -0,0 +1,344 @@
+package experiment
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/robbrit/econerra/scenario"
+)
+
+// A Target is a moment of the simulation output that calibration tries to
+// match.
+type Target struct {
+	// Which series the moment is of, see Series.
+	Series string `json:"series"`
+	// Which statistic of the series after the burn in: "mean" (the default)
+	// or "std" for its standard deviation.
+	Stat string `json:"stat,omitempty"`
+	// The value the moment should have.
+	Value float64 `json:"value"`
+	// How much this target matters compared to the others. Defaults to 1.
+	Weight float64 `json:"weight,omitempty"`
+}
+
+// moment works out a target's moment from a run's series. It's an error if
+// the run has no such series.
+func (t Target) moment(series []string, values [][]float64, burnIn int) (float64, error) {
+	vs := column(series, values, burnIn, t.Series)
+	if vs == nil {
+		return 0, fmt.Errorf("no series %q", t.Series)
+	}
+	b := band(vs, nil)
+	switch t.Stat {
+	case "", "mean":
+		return b.Mean, nil
+	case "std":
+		return b.Std, nil
+	}
+	return 0, fmt.Errorf("unknown statistic %q for %s", t.Stat, t.Series)
+}
+
+// Ways to weight the targets against each other. With WeightRelative, which
+// is the default, each target's error is relative to its value, so targets of
+// different sizes count the same. WeightVariance is the diagonal weighting of
+// the simulated method of moments: each target's error is relative to how
+// much its moment varies between seeds at the starting point, so moments that
+// are measured more precisely count for more. It needs at least two seeds.
+const (
+	WeightRelative = "relative"
+	WeightVariance = "variance"
+)
+
+// A Calibration searches for the parameters that bring a scenario's moments
+// closest to their targets, using the simulated method of moments: every
+// point is run with the same few seeds, the moments are averaged over them,
+// and the Nelder-Mead method looks for the point that minimizes the weighted
+// sum of squared differences from the targets.
+type Calibration struct {
+	Scenario *scenario.Scenario
+	// The parameters to search. The search starts in the middle of their
+	// ranges and stays within them.
+	Params  []Param
+	Targets []Target
+	// How many seeds to average the moments over. Defaults to 1.
+	Seeds int
+	// How many cycles at the start of each run to leave out of the moments.
+	// Defaults to half the run.
+	BurnIn int
+	// How to weight the targets against each other, WeightRelative or
+	// WeightVariance.
+	Weighting string
+	// The search stops after this many points. Defaults to 100.
+	MaxEvaluations int
+	// The search stops once every point it is considering is within this
+	// fraction of the parameters' ranges of the best one, and within this of
+	// its distance. Defaults to 1e-3.
+	Tolerance float64
+	// How many runs to do at the same time. Defaults to GOMAXPROCS.
+	Parallelism int
+}
+
+// A Moment is how close the calibrated scenario came to a target.
+type Moment struct {
+	Target
+	// The moment averaged over the seeds.
+	Simulated float64 `json:"simulated"`
+	// The standard error of the average, 0 with a single seed.
+	StdErr float64 `json:"std_err"`
+	// The weight that the squared error was given in the distance.
+	EffectiveWeight float64 `json:"effective_weight"`
+}
+
+// A Fit is the result of a calibration.
+type Fit struct {
+	// The best values found for the parameters, in the same order.
+	Params []string  `json:"params"`
+	Values []float64 `json:"values"`
+	// The scenario with the best values.
+	Scenario *scenario.Scenario `json:"scenario"`
+	// The weighted sum of squared differences between the moments and their
+	// targets at the best values.
+	Distance float64  `json:"distance"`
+	Moments  []Moment `json:"moments"`
+	// How the search went.
+	Iterations  int  `json:"iterations"`
+	Evaluations int  `json:"evaluations"`
+	Converged   bool `json:"converged"`
+}
+
+// Run searches for the best parameters. Points that don't make a valid
+// scenario are avoided, but a run that fails stops the search.
+func (c *Calibration) Run() (*Fit, error) {
+	if len(c.Params) == 0 || len(c.Targets) == 0 {
+		return nil, fmt.Errorf("calibration needs params and targets")
+	}
+	n := c.Seeds
+	if n <= 0 {
+		n = 1
+	}
+	if c.Weighting == WeightVariance && n < 2 {
+		return nil, fmt.Errorf("variance weighting needs at least 2 seeds, got %d", n)
+	}
+	seeds := Seeds(c.Scenario.Seed, n)
+	burnIn := c.BurnIn
+	if burnIn <= 0 {
+		burnIn = c.Scenario.Cycles / 2
+	}
+	if burnIn >= c.Scenario.Cycles {
+		return nil, fmt.Errorf("burn in of %d cycles leaves nothing of a %d cycle run", burnIn, c.Scenario.Cycles)
+	}
+	maxEvals := c.MaxEvaluations
+	if maxEvals <= 0 {
+		maxEvals = 100
+	}
+	tol := c.Tolerance
+	if tol <= 0 {
+		tol = 1e-3
+	}
+
+	// The search works in the unit cube, so that every parameter moves on the
+	// same scale and staying in range is easy.
+	values := func(x []float64) []float64 {
+		vs := make([]float64, len(x))
+		for i, p := range c.Params {
+			u := math.Max(0, math.Min(1, x[i]))
+			vs[i] = p.round(p.Min + u*(p.Max-p.Min))
+		}
+		return vs
+	}
+	start := make([]float64, len(c.Params))
+	for i := range start {
+		start[i] = 0.5
+	}
+
+	if _, err := c.scenario(values(start)); err != nil {
+		return nil, fmt.Errorf("starting point: %s", err)
+	}
+	moments, err := c.moments(values(start), seeds, burnIn)
+	if err != nil {
+		return nil, err
+	}
+	weights := c.weights(moments)
+
+	distance := func(x []float64) (float64, error) {
+		for _, u := range x {
+			if u < 0 || u > 1 {
+				return math.Inf(1), nil
+			}
+		}
+		ms, err := c.moments(values(x), seeds, burnIn)
+		if err != nil || ms == nil {
+			return math.Inf(1), err
+		}
+		return c.distance(ms, weights), nil
+	}
+	res, err := nelderMead(distance, start, 0.25, tol, maxEvals)
+	if err != nil {
+		return nil, err
+	}
+
+	fit := &Fit{
+		Values:      values(res.X),
+		Distance:    res.F,
+		Iterations:  res.Iterations,
+		Evaluations: res.Evaluations,
+		Converged:   res.Converged,
+	}
+	if fit.Scenario, err = c.scenario(fit.Values); err != nil {
+		return nil, err
+	}
+	for _, p := range c.Params {
+		fit.Params = append(fit.Params, p.Name)
+	}
+	if moments, err = c.moments(fit.Values, seeds, burnIn); err != nil {
+		return nil, err
+	}
+	for i, t := range c.Targets {
+		b := band(moments[i], nil)
+		fit.Moments = append(fit.Moments, Moment{
+			Target:          t,
+			Simulated:       b.Mean,
+			StdErr:          b.Std / math.Sqrt(float64(len(seeds))),
+			EffectiveWeight: weights[i],
+		})
+	}
+	return fit, nil
+}
+
+// scenario gives the scenario with the parameters set to some values.
+func (c *Calibration) scenario(values []float64) (*scenario.Scenario, error) {
+	scen := c.Scenario
+	for i, p := range c.Params {
+		var err error
+		if scen, err = scen.With(p.Name, values[i]); err != nil {
+			return nil, err
+		}
+	}
+	return scen, nil
+}
+
+// moments runs the scenario with the parameters set to some values, once for
+// each seed, giving every target's moment for each seed. It gives nil if the
+// values don't make a valid scenario.
+func (c *Calibration) moments(values []float64, seeds []int64, burnIn int) ([][]float64, error) {
+	base, err := c.scenario(values)
+	if err != nil {
+		return nil, nil
+	}
+
+	moments := make([][]float64, len(c.Targets))
+	for i := range moments {
+		moments[i] = make([]float64, len(seeds))
+	}
+	err = parallel(len(seeds), c.Parallelism, func(s int) error {
+		scen := *base
+		scen.Seed = seeds[s]
+		series, vs, err := run(&scen)
+		if err != nil {
+			return fmt.Errorf("%v with seed %d: %s", values, seeds[s], err)
+		}
+		for i, t := range c.Targets {
+			if moments[i][s], err = t.moment(series, vs, burnIn); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moments, nil
+}
+
+// weights works out how much each target's squared error counts, given the
+// moments at the starting point.
+func (c *Calibration) weights(moments [][]float64) []float64 {
+	ws := make([]float64, len(c.Targets))
+	for i, t := range c.Targets {
+		w := t.Weight
+		if w <= 0 {
+			w = 1
+		}
+
+		scale := 1.0
+		if t.Value != 0 {
+			scale = t.Value * t.Value
+		}
+		if c.Weighting == WeightVariance {
+			// The variance of the average over the seeds.
+			b := band(append([]float64(nil), moments[i]...), nil)
+			if v := b.Std * b.Std / float64(len(moments[i])); v > 0 {
+				scale = v
+			}
+		}
+		ws[i] = w / scale
+	}
+	return ws
+}
+
+// distance gets the weighted sum of squared differences between the average
+// moments and their targets.
+func (c *Calibration) distance(moments [][]float64, weights []float64) float64 {
+	d := 0.0
+	for i, t := range c.Targets {
+		m := band(append([]float64(nil), moments[i]...), nil).Mean
+		d += weights[i] * (m - t.Value) * (m - t.Value)
+	}
+	if math.IsNaN(d) {
+		return math.Inf(1)
+	}
+	return d
+}
+
+// A CalibrationFile describes a calibration in JSON.
+type CalibrationFile struct {
+	Params         []Param  `json:"params"`
+	Targets        []Target `json:"targets"`
+	Seeds          int      `json:"seeds,omitempty"`
+	BurnIn         int      `json:"burn_in,omitempty"`
+	Weighting      string   `json:"weighting,omitempty"`
+	MaxEvaluations int      `json:"max_evaluations,omitempty"`
+	Tolerance      float64  `json:"tolerance,omitempty"`
+}
+
+// LoadCalibration reads a calibration of a scenario from a JSON file.
+func LoadCalibration(path string, scen *scenario.Scenario) (*Calibration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	var cf CalibrationFile
+	if err := dec.Decode(&cf); err != nil {
+		return nil, fmt.Errorf("calibration: %s", err)
+	}
+	for _, p := range cf.Params {
+		if p.Max <= p.Min {
+			return nil, fmt.Errorf("calibration: %s needs a max above its min", p.Name)
+		}
+	}
+	switch cf.Weighting {
+	case "", WeightRelative, WeightVariance:
+	default:
+		return nil, fmt.Errorf("calibration: unknown weighting %q", cf.Weighting)
+	}
+	if cf.Weighting == WeightVariance && cf.Seeds < 2 {
+		return nil, fmt.Errorf("calibration: variance weighting needs at least 2 seeds")
+	}
+
+	return &Calibration{
+		Scenario:       scen,
+		Params:         cf.Params,
+		Targets:        cf.Targets,
+		Seeds:          cf.Seeds,
+		BurnIn:         cf.BurnIn,
+		Weighting:      cf.Weighting,
+		MaxEvaluations: cf.MaxEvaluations,
+		Tolerance:      cf.Tolerance,
+	}, nil
+}
@@ -0,0 +1,119 @@
+package experiment
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestNelderMead(t *testing.T) {
+	calls := 0
+	rosenbrock := func(x []float64) (float64, error) {
+		calls++
+		return 100*math.Pow(x[1]-x[0]*x[0], 2) + math.Pow(1-x[0], 2), nil
+	}
+	res, err := nelderMead(rosenbrock, []float64{-1, 2}, 0.5, 1e-8, 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Converged || math.Abs(res.X[0]-1) > 1e-3 || math.Abs(res.X[1]-1) > 1e-3 {
+		t.Errorf("got %+v, want to converge on (1, 1)", res)
+	}
+	if res.Evaluations != calls {
+		t.Errorf("counted %d evaluations, want %d", res.Evaluations, calls)
+	}
+
+	// Points that can't be evaluated are avoided.
+	bounded := func(x []float64) (float64, error) {
+		if x[0] < 2 {
+			return math.Inf(1), nil
+		}
+		return x[0] * x[0], nil
+	}
+	if res, err = nelderMead(bounded, []float64{3}, 0.5, 1e-6, 500); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(res.X[0]-2) > 1e-3 {
+		t.Errorf("got %v, want to stop at the edge, 2", res.X)
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	scen := smallScenario()
+	scen.Cycles = 20
+	c := &Calibration{
+		Scenario: scen,
+		Params:   []Param{{Name: "goods.Grain.tech", Min: 200, Max: 2000}},
+		Targets: []Target{
+			{Series: "Grain.Volume", Value: 300},
+			{Series: "UnemploymentRate", Stat: "std", Value: 0},
+		},
+		Seeds:          2,
+		Weighting:      WeightVariance,
+		MaxEvaluations: 12,
+	}
+	fit, err := c.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fit.Evaluations < 12 && !fit.Converged {
+		t.Errorf("stopped after %d evaluations without converging", fit.Evaluations)
+	}
+	if len(fit.Values) != 1 || fit.Values[0] < 200 || fit.Values[0] > 2000 {
+		t.Errorf("got values %v, want one in range", fit.Values)
+	}
+	if fit.Scenario.Good(0).Tech != fit.Values[0] {
+		t.Errorf("got scenario with tech %g, want %g", fit.Scenario.Good(0).Tech, fit.Values[0])
+	}
+	if len(fit.Moments) != 2 {
+		t.Fatalf("got moments %+v, want one per target", fit.Moments)
+	}
+	d := 0.0
+	for _, m := range fit.Moments {
+		d += m.EffectiveWeight * (m.Simulated - m.Value) * (m.Simulated - m.Value)
+	}
+	if math.Abs(d-fit.Distance) > 1e-9*math.Max(1, d) {
+		t.Errorf("got distance %g, want %g from the moments", fit.Distance, d)
+	}
+
+	// The search can't do worse than where it started.
+	start, err := (&Calibration{Scenario: scen, Params: c.Params, Targets: c.Targets, Seeds: 2, Weighting: WeightVariance, MaxEvaluations: 1}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fit.Distance > start.Distance {
+		t.Errorf("got distance %g, worse than %g at the start", fit.Distance, start.Distance)
+	}
+
+	c.Targets = []Target{{Series: "Gold.Volume", Value: 1}}
+	if _, err := c.Run(); err == nil {
+		t.Error("got no error for a target with no series")
+	}
+
+	// One seed gives no variance to weight by.
+	c.Seeds = 0
+	if _, err := c.Run(); err == nil {
+		t.Error("got no error for variance weighting with one seed")
+	}
+}
+
+func TestLoadCalibration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calibration.json")
+	spec := `{"params": [{"name": "elasticity", "min": 0.5, "max": 2}], "targets": [{"series": "UnemploymentRate", "value": 0.05}], "weighting": "sideways"}`
+	if err := ioutil.WriteFile(path, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCalibration(path, smallScenario()); err == nil {
+		t.Error("got no error for an unknown weighting")
+	}
+
+	spec = `{"params": [{"name": "elasticity", "min": 0.5, "max": 2}], "targets": [{"series": "UnemploymentRate", "value": 0.05}], "weighting": "variance"}`
+	if err := ioutil.WriteFile(path, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCalibration(path, smallScenario()); err == nil {
+		t.Error("got no error for variance weighting with one seed")
+	}
+}
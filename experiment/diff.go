@@ -0,0 +1,178 @@
+package experiment
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// A Run is every series of a single simulation, cycle by cycle.
+type Run struct {
+	Series []string
+	// Values[cycle][i] is the value of Series[i] in that cycle.
+	Values [][]float64
+}
+
+// ReadCSV reads a run back from the files written by the CSV output: the
+// market rows, and the macroeconomic statistics if macro isn't nil. Market
+// series are named after the good and the column, e.g. "Grain.Bid", and
+// macroeconomic series are just the column, e.g. "CPI".
+func ReadCSV(market, macro io.Reader) (*Run, error) {
+	run := &Run{}
+	if err := run.readCSV(market, true); err != nil {
+		return nil, fmt.Errorf("market rows: %s", err)
+	}
+	if macro != nil {
+		if err := run.readCSV(macro, false); err != nil {
+			return nil, fmt.Errorf("macro rows: %s", err)
+		}
+	}
+	return run, nil
+}
+
+// readCSV adds the series from a CSV with an Iteration column, and a Good
+// column if byGood is set, in which case there is a row per good each cycle.
+func (run *Run) readCSV(r io.Reader, byGood bool) error {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("no header")
+	}
+	header := rows[0]
+	first := 1
+	if byGood {
+		first = 2
+	}
+	if len(header) < first || header[0] != "Iteration" || (byGood && header[1] != "Good") {
+		return fmt.Errorf("unexpected header %v", header)
+	}
+
+	// Where each good and column goes in the cycle's values, added as they're
+	// first seen.
+	index := map[string]int{}
+	for _, row := range rows[1:] {
+		cycle, err := strconv.Atoi(row[0])
+		if err != nil {
+			return fmt.Errorf("bad iteration %q", row[0])
+		}
+		if cycle < 0 {
+			return fmt.Errorf("bad iteration %d", cycle)
+		}
+		for len(run.Values) <= cycle {
+			run.Values = append(run.Values, nil)
+		}
+
+		for i, v := range row[first:] {
+			name := header[first+i]
+			if byGood {
+				name = row[1] + "." + name
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("%s in iteration %d is %q, not a number", name, cycle, v)
+			}
+			j, ok := index[name]
+			if !ok {
+				j = len(run.Series)
+				index[name] = j
+				run.Series = append(run.Series, name)
+			}
+			values := run.Values[cycle]
+			for len(values) <= j {
+				// Padded with missing values for series this cycle hasn't had.
+				values = append(values, math.NaN())
+			}
+			values[j] = f
+			run.Values[cycle] = values
+		}
+	}
+
+	// Series are missing from cycles that didn't have a row for them.
+	for cycle, values := range run.Values {
+		for len(values) < len(run.Series) {
+			values = append(values, math.NaN())
+		}
+		run.Values[cycle] = values
+	}
+	return nil
+}
+
+// A Tolerance is how far apart two values can be and still count as the
+// same: within Absolute of each other, or within Relative of the larger of
+// the two.
+type Tolerance struct {
+	Absolute float64
+	Relative float64
+}
+
+// equal checks whether two values are the same within the tolerance. Missing
+// values, which are NaN, are only the same as each other.
+func (t Tolerance) equal(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	d := math.Abs(a - b)
+	return d <= t.Absolute || d <= t.Relative*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// A Difference is a series that isn't the same in two runs.
+type Difference struct {
+	Series string
+	// The first cycle that the series differs in, and its values there.
+	Cycle int
+	A, B  float64
+	// How many cycles it differs in, and the largest difference.
+	Cycles  int
+	Largest float64
+}
+
+// Diff compares two runs series by series, giving every series that differs
+// by more than the tolerance in any cycle, in the order of a's series. It's an
+// error if the runs don't have the same series or the same number of cycles.
+func Diff(a, b *Run, tol Tolerance) ([]Difference, error) {
+	if len(a.Values) != len(b.Values) {
+		return nil, fmt.Errorf("runs have %d and %d cycles", len(a.Values), len(b.Values))
+	}
+	index := map[string]int{}
+	for i, s := range b.Series {
+		index[s] = i
+	}
+	for _, s := range a.Series {
+		if _, ok := index[s]; !ok {
+			return nil, fmt.Errorf("no series %s in the second run", s)
+		}
+	}
+	if len(a.Series) != len(b.Series) {
+		return nil, fmt.Errorf("runs have %d and %d series", len(a.Series), len(b.Series))
+	}
+
+	var diffs []Difference
+	for i, s := range a.Series {
+		j := index[s]
+		d := Difference{Series: s}
+		for cycle := range a.Values {
+			va, vb := a.Values[cycle][i], b.Values[cycle][j]
+			if tol.equal(va, vb) {
+				continue
+			}
+			if d.Cycles == 0 {
+				d.Cycle, d.A, d.B = cycle, va, vb
+			}
+			d.Cycles++
+			diff := math.Abs(va - vb)
+			if math.IsNaN(diff) {
+				// Only one of them has a value.
+				diff = math.Inf(1)
+			}
+			d.Largest = math.Max(d.Largest, diff)
+		}
+		if d.Cycles > 0 {
+			diffs = append(diffs, d)
+		}
+	}
+	return diffs, nil
+}
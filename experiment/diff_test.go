@@ -0,0 +1,83 @@
+package experiment
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	market := "Iteration,Good,Bid,Ask\n0,Grain,1,2\n0,Meat,3,4\n1,Grain,5,6\n1,Meat,7,8\n"
+	macro := "Iteration,CPI\n0,100\n1,101.5\n"
+
+	run, err := ReadCSV(strings.NewReader(market), strings.NewReader(macro))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Grain.Bid", "Grain.Ask", "Meat.Bid", "Meat.Ask", "CPI"}; !reflect.DeepEqual(run.Series, want) {
+		t.Errorf("got series %v, want %v", run.Series, want)
+	}
+	if want := [][]float64{{1, 2, 3, 4, 100}, {5, 6, 7, 8, 101.5}}; !reflect.DeepEqual(run.Values, want) {
+		t.Errorf("got values %v, want %v", run.Values, want)
+	}
+
+	if _, err := ReadCSV(strings.NewReader("Iteration,Good,Bid\n0,Grain,x\n"), nil); err == nil {
+		t.Error("ReadCSV() accepted a value that isn't a number")
+	}
+	if _, err := ReadCSV(strings.NewReader("Good,Bid\nGrain,1\n"), nil); err == nil {
+		t.Error("ReadCSV() accepted a file without iterations")
+	}
+
+	// A good that only shows up part way through is missing before then.
+	run, err = ReadCSV(strings.NewReader("Iteration,Good,Bid\n0,Grain,1\n1,Grain,2\n1,Meat,3\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := run.Values[0][1]; !math.IsNaN(v) {
+		t.Errorf("got %g for a good that wasn't there, want NaN", v)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := &Run{
+		Series: []string{"Grain.Bid", "CPI"},
+		Values: [][]float64{{10, 100}, {11, 101}, {12, 102}},
+	}
+	b := &Run{
+		Series: []string{"CPI", "Grain.Bid"},
+		Values: [][]float64{{100, 10}, {101.001, 11}, {103, 15}},
+	}
+
+	diffs, err := Diff(a, b, Tolerance{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Difference{
+		{Series: "Grain.Bid", Cycle: 2, A: 12, B: 15, Cycles: 1, Largest: 3},
+		{Series: "CPI", Cycle: 1, A: 101, B: 101.001, Cycles: 2, Largest: 1},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("got %+v, want %+v", diffs, want)
+	}
+
+	if diffs, err = Diff(a, b, Tolerance{Relative: 0.01}); err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].Series != "Grain.Bid" {
+		t.Errorf("got %+v with a relative tolerance, want only Grain.Bid", diffs)
+	}
+	if diffs, err = Diff(a, b, Tolerance{Absolute: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("got %+v with an absolute tolerance, want nothing", diffs)
+	}
+
+	if _, err := Diff(a, &Run{Series: a.Series, Values: a.Values[:2]}, Tolerance{}); err == nil {
+		t.Error("Diff() accepted runs of different lengths")
+	}
+	if _, err := Diff(a, &Run{Series: []string{"Grain.Bid", "RealGDP"}, Values: a.Values}, Tolerance{}); err == nil {
+		t.Error("Diff() accepted runs with different series")
+	}
+}
@@ -0,0 +1,246 @@
+package experiment
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/robbrit/econerra/scenario"
+	"github.com/robbrit/econerra/sim"
+)
+
+// DefaultQuantiles are the quantiles reported when none are given: the median,
+// the quartiles, and a 90% band.
+var DefaultQuantiles = []float64{0.05, 0.25, 0.5, 0.75, 0.95}
+
+// A MonteCarlo runs many replications of a scenario that only differ in their
+// random seeds, and summarizes every series cycle by cycle.
+type MonteCarlo struct {
+	Scenario *scenario.Scenario
+	// How many replications to run.
+	Runs int
+	// How many replications to run at the same time. Defaults to GOMAXPROCS.
+	Parallelism int
+	// Which quantiles to report, as fractions. Defaults to DefaultQuantiles.
+	Quantiles []float64
+}
+
+// Seeds gives the seeds for n replications. They are drawn from a single seed,
+// so the same batch can be run again.
+func Seeds(seed int64, n int) []int64 {
+	r := rand.New(rand.NewSource(seed))
+	seeds := make([]int64, n)
+	for i := range seeds {
+		seeds[i] = r.Int63()
+	}
+	return seeds
+}
+
+// Run runs all the replications for the scenario's number of cycles, stopping
+// at the first one that fails.
+func (mc *MonteCarlo) Run() (*Summary, error) {
+	seeds := Seeds(mc.Scenario.Seed, mc.Runs)
+	var series []string
+	runs := make([][][]float64, len(seeds))
+
+	err := parallel(len(seeds), mc.Parallelism, func(i int) error {
+		scen := *mc.Scenario
+		scen.Seed = seeds[i]
+		names, values, err := run(&scen)
+		if err != nil {
+			return fmt.Errorf("run with seed %d: %s", seeds[i], err)
+		}
+		if i == 0 {
+			series = names
+		}
+		runs[i] = values
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	quantiles := mc.Quantiles
+	if quantiles == nil {
+		quantiles = DefaultQuantiles
+	}
+	return summarize(series, runs, quantiles), nil
+}
+
+// run runs a single simulation, giving the names of its series and their
+// values for every cycle.
+func run(scen *scenario.Scenario) ([]string, [][]float64, error) {
+	var names []string
+	var values [][]float64
+
+	s, err := sim.New(scen)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.AddObserver(sim.CycleFunc(func(res *sim.CycleResult) error {
+		if names == nil {
+			names = Series(res)
+		}
+		values = append(values, Values(res))
+		return nil
+	}))
+	_, err = s.Run(scen.Cycles)
+	return names, values, err
+}
+
+// parallel calls fn for every number below n, running up to workers of them at
+// the same time, or GOMAXPROCS if workers isn't positive. Once a call fails no
+// more are started, and the first error is returned.
+func parallel(n, workers int, fn func(i int) error) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		mu     sync.Mutex
+		next   int
+		failed error
+		wg     sync.WaitGroup
+	)
+	// take hands out the next number to work on, or -1 when there's nothing left.
+	take := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		if next == n || failed != nil {
+			return -1
+		}
+		next++
+		return next - 1
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := take(); i >= 0; i = take() {
+				if err := fn(i); err != nil {
+					mu.Lock()
+					if failed == nil {
+						failed = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return failed
+}
+
+// A Band describes the spread of one series in one cycle across replications.
+type Band struct {
+	Mean float64
+	// The sample standard deviation, 0 for a single replication.
+	Std float64
+	// One value for each of the summary's quantiles.
+	Quantiles []float64
+}
+
+// A Summary describes how every series varied across replications, cycle by
+// cycle.
+type Summary struct {
+	Runs      int
+	Quantiles []float64
+	Series    []string
+	// Bands[cycle][i] is the band for Series[i] in that cycle.
+	Bands [][]Band
+}
+
+// summarize works out the bands from the values of every run, indexed by run,
+// then cycle, then series.
+func summarize(series []string, runs [][][]float64, quantiles []float64) *Summary {
+	s := &Summary{Runs: len(runs), Quantiles: quantiles, Series: series}
+	if len(runs) == 0 {
+		return s
+	}
+
+	sample := make([]float64, len(runs))
+	for cycle := range runs[0] {
+		bands := make([]Band, len(series))
+		for i := range series {
+			for r := range runs {
+				sample[r] = runs[r][cycle][i]
+			}
+			bands[i] = band(sample, quantiles)
+		}
+		s.Bands = append(s.Bands, bands)
+	}
+	return s
+}
+
+// band summarizes a sample, sorting it along the way.
+func band(sample []float64, quantiles []float64) Band {
+	b := Band{Quantiles: make([]float64, len(quantiles))}
+	for _, v := range sample {
+		b.Mean += v
+	}
+	b.Mean /= float64(len(sample))
+
+	if len(sample) > 1 {
+		for _, v := range sample {
+			b.Std += (v - b.Mean) * (v - b.Mean)
+		}
+		b.Std = math.Sqrt(b.Std / float64(len(sample)-1))
+	}
+
+	sort.Float64s(sample)
+	for i, q := range quantiles {
+		b.Quantiles[i] = quantile(sample, q)
+	}
+	return b
+}
+
+// quantile gets a quantile of a sorted sample, interpolating linearly between
+// the closest values.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	if lo >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	if lo < 0 {
+		return sorted[0]
+	}
+	frac := pos - float64(lo)
+	return sorted[lo] + frac*(sorted[lo+1]-sorted[lo])
+}
+
+// WriteCSV writes the summary as a table with one row per cycle and series,
+// with columns Cycle, Series, Runs, Mean, Std, then one per quantile named
+// after its percentage, e.g. Q5 and Q50.
+func (s *Summary) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	header := []string{"Cycle", "Series", "Runs", "Mean", "Std"}
+	for _, q := range s.Quantiles {
+		header = append(header, fmt.Sprintf("Q%g", q*100))
+	}
+	out.Write(header)
+
+	for cycle, bands := range s.Bands {
+		for i, b := range bands {
+			row := []string{
+				fmt.Sprintf("%d", cycle),
+				s.Series[i],
+				fmt.Sprintf("%d", s.Runs),
+				fmt.Sprintf("%g", b.Mean),
+				fmt.Sprintf("%g", b.Std),
+			}
+			for _, v := range b.Quantiles {
+				row = append(row, fmt.Sprintf("%g", v))
+			}
+			out.Write(row)
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
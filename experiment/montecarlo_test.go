@@ -0,0 +1,109 @@
+package experiment
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/robbrit/econerra/scenario"
+)
+
+func smallScenario() *scenario.Scenario {
+	scen := scenario.Default()
+	scen.Workers = 50
+	scen.Cycles = 10
+	return scen
+}
+
+func TestBand(t *testing.T) {
+	b := band([]float64{4, 1, 3, 2}, []float64{0, 0.5, 0.9, 1})
+	if b.Mean != 2.5 {
+		t.Errorf("got mean %g, want 2.5", b.Mean)
+	}
+	if want := math.Sqrt(5.0 / 3); math.Abs(b.Std-want) > 1e-12 {
+		t.Errorf("got std %g, want %g", b.Std, want)
+	}
+	want := []float64{1, 2.5, 3.7, 4}
+	for i := range want {
+		if math.Abs(b.Quantiles[i]-want[i]) > 1e-12 {
+			t.Errorf("got quantiles %v, want %v", b.Quantiles, want)
+			break
+		}
+	}
+
+	if b := band([]float64{7}, []float64{0.5}); b.Mean != 7 || b.Std != 0 || b.Quantiles[0] != 7 {
+		t.Errorf("got %+v for a single value", b)
+	}
+}
+
+func TestMonteCarlo(t *testing.T) {
+	mc := &MonteCarlo{Scenario: smallScenario(), Runs: 6, Parallelism: 4}
+	summary, err := mc.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Bands) != 10 {
+		t.Fatalf("got %d cycles, want 10", len(summary.Bands))
+	}
+	if summary.Runs != 6 || !reflect.DeepEqual(summary.Quantiles, DefaultQuantiles) {
+		t.Errorf("got %d runs and quantiles %v", summary.Runs, summary.Quantiles)
+	}
+
+	varied := false
+	for _, bands := range summary.Bands {
+		for _, b := range bands {
+			varied = varied || b.Std > 0
+		}
+	}
+	if !varied {
+		t.Error("every series was the same in every run, want the seeds to make a difference")
+	}
+
+	// Running one at a time gives the same answer.
+	mc.Parallelism = 1
+	serial, err := mc.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(summary, serial) {
+		t.Error("got different summaries running in parallel and serially")
+	}
+
+	var buf bytes.Buffer
+	if err := summary.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Cycle", "Series", "Runs", "Mean", "Std", "Q5", "Q25", "Q50", "Q75", "Q95"}; !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("got header %v, want %v", rows[0], want)
+	}
+	if got, want := len(rows), 1+10*len(summary.Series); got != want {
+		t.Errorf("got %d rows, want %d", got, want)
+	}
+}
+
+func TestParallel(t *testing.T) {
+	done := make([]bool, 100)
+	if err := parallel(len(done), 0, func(i int) error {
+		done[i] = true
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for i, d := range done {
+		if !d {
+			t.Errorf("%d was never run", i)
+		}
+	}
+
+	failure := errors.New("failed")
+	if err := parallel(10, 3, func(i int) error { return failure }); err != failure {
+		t.Errorf("got %v, want the first failure", err)
+	}
+}
@@ -0,0 +1,152 @@
+package experiment
+
+import (
+	"math"
+	"sort"
+)
+
+// nelderMeadResult is where a Nelder-Mead search ended up.
+type nelderMeadResult struct {
+	X           []float64
+	F           float64
+	Iterations  int
+	Evaluations int
+	// Whether the simplex shrank below the tolerance before running out of
+	// evaluations.
+	Converged bool
+}
+
+// nelderMead looks for the minimum of f, starting from a simplex around x0
+// that is step wide along each axis. It stops once the best and worst points
+// of the simplex are within tol of each other in both position and value, or
+// after maxEvals evaluations. An error from f stops the search.
+//
+// f can return +Inf for points that it can't evaluate, which the search will
+// then move away from.
+func nelderMead(f func([]float64) (float64, error), x0 []float64, step, tol float64, maxEvals int) (*nelderMeadResult, error) {
+	const (
+		reflection  = 1.0
+		expansion   = 2.0
+		contraction = 0.5
+		shrinkage   = 0.5
+	)
+
+	n := len(x0)
+	res := &nelderMeadResult{}
+	eval := func(x []float64) (float64, error) {
+		res.Evaluations++
+		return f(x)
+	}
+
+	simplex := make([]vertex, n+1)
+	for i := range simplex {
+		x := append([]float64(nil), x0...)
+		if i > 0 {
+			x[i-1] += step
+		}
+		fx, err := eval(x)
+		if err != nil {
+			return nil, err
+		}
+		simplex[i] = vertex{x, fx}
+	}
+
+	// along gives the point at t times the way from the centroid c to x.
+	along := func(c, x []float64, t float64) []float64 {
+		p := make([]float64, n)
+		for i := range p {
+			p[i] = c[i] + t*(x[i]-c[i])
+		}
+		return p
+	}
+
+	for res.Evaluations < maxEvals {
+		sort.SliceStable(simplex, func(i, j int) bool { return simplex[i].f < simplex[j].f })
+		best, worst := simplex[0], simplex[n]
+		if converged(simplex, tol) {
+			res.Converged = true
+			break
+		}
+		res.Iterations++
+
+		centroid := make([]float64, n)
+		for _, v := range simplex[:n] {
+			for i := range centroid {
+				centroid[i] += v.x[i] / float64(n)
+			}
+		}
+
+		xr := along(centroid, worst.x, -reflection)
+		fr, err := eval(xr)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case fr < best.f:
+			xe := along(centroid, worst.x, -expansion)
+			fe, err := eval(xe)
+			if err != nil {
+				return nil, err
+			}
+			if fe < fr {
+				simplex[n] = vertex{xe, fe}
+			} else {
+				simplex[n] = vertex{xr, fr}
+			}
+			continue
+		case fr < simplex[n-1].f:
+			simplex[n] = vertex{xr, fr}
+			continue
+		}
+
+		// Contract towards whichever of the reflected and worst points is better.
+		xc := along(centroid, worst.x, contraction)
+		if fr < worst.f {
+			xc = along(centroid, xr, contraction)
+		}
+		fc, err := eval(xc)
+		if err != nil {
+			return nil, err
+		}
+		if fc < math.Min(fr, worst.f) {
+			simplex[n] = vertex{xc, fc}
+			continue
+		}
+
+		for i := 1; i <= n; i++ {
+			x := along(best.x, simplex[i].x, shrinkage)
+			fx, err := eval(x)
+			if err != nil {
+				return nil, err
+			}
+			simplex[i] = vertex{x, fx}
+		}
+	}
+
+	sort.SliceStable(simplex, func(i, j int) bool { return simplex[i].f < simplex[j].f })
+	res.X, res.F = simplex[0].x, simplex[0].f
+	return res, nil
+}
+
+// A vertex is a corner of the simplex, and the value of the function there.
+type vertex struct {
+	x []float64
+	f float64
+}
+
+// converged checks whether every corner of a sorted simplex is within tol of
+// the best one, in both position and value.
+func converged(simplex []vertex, tol float64) bool {
+	best := simplex[0]
+	for _, v := range simplex[1:] {
+		if math.IsInf(v.f, 1) || math.Abs(v.f-best.f) > tol {
+			return false
+		}
+		for i := range best.x {
+			if math.Abs(v.x[i]-best.x[i]) > tol {
+				return false
+			}
+		}
+	}
+	return true
+}
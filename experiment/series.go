@@ -0,0 +1,94 @@
+// Package experiment runs many simulations at once and summarizes what they
+// have in common, for studying the model rather than a single run of it.
+//
+// Every run builds its own markets and agents, so runs happen at the same
+// time. Settings shared by the whole program, like the market precision, have
+// to be set before running; runs fail if the precision isn't the scenario's.
+package experiment
+
+import (
+	"fmt"
+
+	"github.com/robbrit/econerra/sim"
+)
+
+// Series names every number that a cycle produces, in the order that Values
+// gives them. Market series are named after the good and the column in the
+// CSV output, e.g. "Grain.Bid"; macroeconomic series are just the column, e.g.
+// "CPI".
+func Series(res *sim.CycleResult) []string {
+	var names []string
+	for _, r := range res.Markets {
+		for _, column := range marketColumns {
+			names = append(names, fmt.Sprintf("%s.%s", r.Good, column))
+		}
+	}
+	return append(names, macroColumns...)
+}
+
+var marketColumns = []string{
+	"Bid", "Ask", "Low", "High", "Volume", "Open", "Close", "VWAP", "Trades",
+	"Supply", "Demand", "Entries", "Exits",
+	"Vacancies", "Hires", "Quits", "Layoffs", "Expiries", "Rejected",
+}
+
+var macroColumns = []string{
+	"NominalGDP", "RealGDP", "CPI", "Inflation", "UnemploymentRate", "AverageWage", "RealWage",
+	"WageShare", "PriceDispersion",
+}
+
+// Values gets every number that a cycle produced, see Series.
+func Values(res *sim.CycleResult) []float64 {
+	var vs []float64
+	for _, r := range res.Markets {
+		vs = append(vs,
+			r.Bid.Float(),
+			r.Ask.Float(),
+			r.Low.Float(),
+			r.High.Float(),
+			r.Volume.Float(),
+			r.Open.Float(),
+			r.Close.Float(),
+			r.VWAP,
+			float64(r.Trades),
+			r.Supply.Float(),
+			r.Demand.Float(),
+			float64(r.Entries),
+			float64(r.Exits),
+			float64(r.Vacancies),
+			float64(r.Hires),
+			float64(r.Quits),
+			float64(r.Layoffs),
+			float64(r.Expiries),
+			float64(r.Rejections.Total()),
+		)
+	}
+	m := res.Macro
+	return append(vs,
+		m.NominalGDP,
+		m.RealGDP,
+		m.CPI,
+		m.Inflation,
+		m.UnemploymentRate,
+		m.AverageWage,
+		m.RealWage,
+		m.WageShare,
+		m.PriceDispersion,
+	)
+}
+
+// column gets the values of one series from a run's values, leaving out the
+// first burnIn cycles. It's nil if there is no such series.
+func column(series []string, values [][]float64, burnIn int, name string) []float64 {
+	for i, s := range series {
+		if s != name {
+			continue
+		}
+		vs := make([]float64, 0, len(values)-burnIn)
+		for _, cycle := range values[burnIn:] {
+			vs = append(vs, cycle[i])
+		}
+		return vs
+	}
+	return nil
+}
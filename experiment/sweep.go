@@ -0,0 +1,272 @@
+package experiment
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+
+	"github.com/robbrit/econerra/scenario"
+)
+
+// A Param is a scenario parameter to vary, and what to vary it over.
+type Param struct {
+	// Where the parameter is in the scenario file, see scenario.With.
+	Name string `json:"name"`
+	// The range of values to try.
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	// How many evenly spaced values from Min to Max a grid tries.
+	Points int `json:"points,omitempty"`
+	// The exact values for a grid to try, instead of a range.
+	Values []float64 `json:"values,omitempty"`
+	// If positive, values are rounded to a multiple of this, e.g. 1 for
+	// parameters that must be whole numbers.
+	Round float64 `json:"round,omitempty"`
+}
+
+func (p Param) round(v float64) float64 {
+	if p.Round <= 0 {
+		return v
+	}
+	return math.Round(v/p.Round) * p.Round
+}
+
+// values gets the values a grid tries for the parameter.
+func (p Param) values() []float64 {
+	if len(p.Values) > 0 {
+		return p.Values
+	}
+	if p.Points < 2 {
+		return []float64{p.round(p.Min)}
+	}
+	vs := make([]float64, p.Points)
+	for i := range vs {
+		vs[i] = p.round(p.Min + (p.Max-p.Min)*float64(i)/float64(p.Points-1))
+	}
+	return vs
+}
+
+// Grid gives every combination of the parameters' values. Each point has one
+// value per parameter, in the same order, and the last parameter changes the
+// fastest.
+func Grid(params []Param) [][]float64 {
+	points := [][]float64{nil}
+	for _, p := range params {
+		var next [][]float64
+		for _, point := range points {
+			for _, v := range p.values() {
+				next = append(next, append(append([]float64(nil), point...), v))
+			}
+		}
+		points = next
+	}
+	return points
+}
+
+// LatinHypercube gives n points spread over the parameters' ranges: each range
+// is cut into n equal slices, and each slice of each range is used by exactly
+// one point, at a random place within it.
+func LatinHypercube(params []Param, n int, seed int64) [][]float64 {
+	r := rand.New(rand.NewSource(seed))
+	points := make([][]float64, n)
+	for i := range points {
+		points[i] = make([]float64, len(params))
+	}
+	for j, p := range params {
+		for i, slice := range r.Perm(n) {
+			u := (float64(slice) + r.Float64()) / float64(n)
+			points[i][j] = p.round(p.Min + u*(p.Max-p.Min))
+		}
+	}
+	return points
+}
+
+// Metrics are what a sweep reports about each run, all measured after the
+// burn in:
+//   - Unemployment is the average unemployment rate.
+//   - Inflation is the average inflation.
+//   - PriceVolatility is the standard deviation of inflation.
+//   - RealGDP and RealWage are their averages.
+var Metrics = []string{"Unemployment", "Inflation", "PriceVolatility", "RealGDP", "RealWage"}
+
+// metrics works out the Metrics from a run's series, leaving out the first
+// burnIn cycles.
+func metrics(series []string, values [][]float64, burnIn int) []float64 {
+	mean := func(vs []float64) float64 {
+		if len(vs) == 0 {
+			return 0
+		}
+		return band(vs, nil).Mean
+	}
+
+	inflation := column(series, values, burnIn, "Inflation")
+	volatility := 0.0
+	if len(inflation) > 0 {
+		volatility = band(inflation, nil).Std
+	}
+	return []float64{
+		mean(column(series, values, burnIn, "UnemploymentRate")),
+		mean(inflation),
+		volatility,
+		mean(column(series, values, burnIn, "RealGDP")),
+		mean(column(series, values, burnIn, "RealWage")),
+	}
+}
+
+// A Sweep runs a scenario at many points in its parameter space, possibly
+// with several seeds each, and reports the Metrics for every run.
+type Sweep struct {
+	Scenario *scenario.Scenario
+	Params   []Param
+	// The values of the parameters at each point, e.g. from Grid or
+	// LatinHypercube.
+	Points [][]float64
+	// How many seeds to run each point with. Defaults to 1. Every point uses
+	// the same seeds, so that differences between points aren't down to
+	// chance.
+	Seeds int
+	// How many runs to do at the same time. Defaults to GOMAXPROCS.
+	Parallelism int
+	// How many cycles at the start of each run to leave out of the metrics,
+	// while the economy settles down. Defaults to half the run.
+	BurnIn int
+}
+
+// Run runs every point with every seed, stopping at the first run that fails.
+// Points that don't make a valid scenario are an error before anything is run.
+func (sw *Sweep) Run() (*Table, error) {
+	n := sw.Seeds
+	if n <= 0 {
+		n = 1
+	}
+	seeds := Seeds(sw.Scenario.Seed, n)
+	burnIn := sw.BurnIn
+	if burnIn <= 0 {
+		burnIn = sw.Scenario.Cycles / 2
+	}
+	if burnIn >= sw.Scenario.Cycles {
+		return nil, fmt.Errorf("burn in of %d cycles leaves nothing of a %d cycle run", burnIn, sw.Scenario.Cycles)
+	}
+
+	scens := make([]*scenario.Scenario, len(sw.Points))
+	for i, point := range sw.Points {
+		scen := sw.Scenario
+		for j, p := range sw.Params {
+			var err error
+			if scen, err = scen.With(p.Name, point[j]); err != nil {
+				return nil, fmt.Errorf("point %d: %s", i, err)
+			}
+		}
+		scens[i] = scen
+	}
+
+	t := &Table{Metrics: Metrics}
+	for _, p := range sw.Params {
+		t.Params = append(t.Params, p.Name)
+	}
+	t.Rows = make([]Row, len(scens)*len(seeds))
+	err := parallel(len(t.Rows), sw.Parallelism, func(i int) error {
+		point, seed := i/len(seeds), seeds[i%len(seeds)]
+		scen := *scens[point]
+		scen.Seed = seed
+		series, values, err := run(&scen)
+		if err != nil {
+			return fmt.Errorf("point %d with seed %d: %s", point, seed, err)
+		}
+		t.Rows[i] = Row{
+			Point:   point,
+			Values:  sw.Points[point],
+			Seed:    seed,
+			Metrics: metrics(series, values, burnIn),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// A Row is the result of running a single point with a single seed.
+type Row struct {
+	Point   int
+	Values  []float64
+	Seed    int64
+	Metrics []float64
+}
+
+// A Table is the result of a sweep, with rows in point order, then seed order.
+type Table struct {
+	Params  []string
+	Metrics []string
+	Rows    []Row
+}
+
+// WriteCSV writes the table with one row per point, seed and metric, with
+// columns Point, then one per parameter, then Seed, Metric and Value.
+func (t *Table) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	header := append([]string{"Point"}, t.Params...)
+	out.Write(append(header, "Seed", "Metric", "Value"))
+
+	for _, r := range t.Rows {
+		for i, m := range t.Metrics {
+			row := []string{fmt.Sprintf("%d", r.Point)}
+			for _, v := range r.Values {
+				row = append(row, fmt.Sprintf("%g", v))
+			}
+			out.Write(append(row,
+				fmt.Sprintf("%d", r.Seed),
+				m,
+				fmt.Sprintf("%g", r.Metrics[i]),
+			))
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// A SweepFile describes a sweep in JSON. It runs a grid of the parameters'
+// values, or Latin hypercube samples of their ranges if Samples is positive.
+type SweepFile struct {
+	Params  []Param `json:"params"`
+	Samples int     `json:"samples,omitempty"`
+	Seeds   int     `json:"seeds,omitempty"`
+	BurnIn  int     `json:"burn_in,omitempty"`
+}
+
+// LoadSweep reads a sweep of a scenario from a JSON file.
+func LoadSweep(path string, scen *scenario.Scenario) (*Sweep, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	var sf SweepFile
+	if err := dec.Decode(&sf); err != nil {
+		return nil, fmt.Errorf("sweep: %s", err)
+	}
+	if len(sf.Params) == 0 {
+		return nil, fmt.Errorf("sweep: no params")
+	}
+	for _, p := range sf.Params {
+		if p.Max < p.Min {
+			return nil, fmt.Errorf("sweep: %s has max below min", p.Name)
+		}
+	}
+
+	sw := &Sweep{Scenario: scen, Params: sf.Params, Seeds: sf.Seeds, BurnIn: sf.BurnIn}
+	if sf.Samples > 0 {
+		sw.Points = LatinHypercube(sf.Params, sf.Samples, scen.Seed)
+	} else {
+		sw.Points = Grid(sf.Params)
+	}
+	return sw, nil
+}
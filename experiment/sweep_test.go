@@ -0,0 +1,120 @@
+package experiment
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGrid(t *testing.T) {
+	got := Grid([]Param{
+		{Name: "a", Min: 0, Max: 1, Points: 3},
+		{Name: "b", Values: []float64{5, 7}},
+	})
+	want := [][]float64{{0, 5}, {0, 7}, {0.5, 5}, {0.5, 7}, {1, 5}, {1, 7}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got := Grid([]Param{{Name: "a", Min: 1, Max: 2, Points: 4, Round: 1}}); !reflect.DeepEqual(got, [][]float64{{1}, {1}, {2}, {2}}) {
+		t.Errorf("got %v for a rounded grid", got)
+	}
+}
+
+func TestLatinHypercube(t *testing.T) {
+	params := []Param{{Name: "a", Min: 0, Max: 10}, {Name: "b", Min: 100, Max: 200}}
+	points := LatinHypercube(params, 5, 1)
+	if len(points) != 5 {
+		t.Fatalf("got %d points, want 5", len(points))
+	}
+
+	for j, p := range params {
+		used := make([]bool, 5)
+		for _, point := range points {
+			slice := int((point[j] - p.Min) / (p.Max - p.Min) * 5)
+			if slice < 0 || slice >= 5 || used[slice] {
+				t.Errorf("%s: got %v, want one value in each fifth of the range", p.Name, points)
+				break
+			}
+			used[slice] = true
+		}
+	}
+
+	if !reflect.DeepEqual(points, LatinHypercube(params, 5, 1)) {
+		t.Error("got different samples from the same seed")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	params := []Param{{Name: "elasticity", Values: []float64{0.6, 1.2}}}
+	sw := &Sweep{Scenario: smallScenario(), Params: params, Points: Grid(params), Seeds: 2}
+	table, err := sw.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(table.Rows) != 4 {
+		t.Fatalf("got %d rows, want one per point and seed", len(table.Rows))
+	}
+	for i, r := range table.Rows {
+		if r.Point != i/2 || r.Values[0] != params[0].Values[i/2] {
+			t.Errorf("row %d is for point %d with %v", i, r.Point, r.Values)
+		}
+		if len(r.Metrics) != len(Metrics) {
+			t.Errorf("row %d has metrics %v", i, r.Metrics)
+		}
+		for _, m := range r.Metrics {
+			if math.IsNaN(m) {
+				t.Errorf("row %d has metrics %v", i, r.Metrics)
+			}
+		}
+	}
+	if table.Rows[0].Seed != table.Rows[2].Seed || table.Rows[0].Seed == table.Rows[1].Seed {
+		t.Error("want every point to use the same seeds")
+	}
+
+	var buf bytes.Buffer
+	if err := table.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Point", "elasticity", "Seed", "Metric", "Value"}; !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("got header %v, want %v", rows[0], want)
+	}
+	if len(rows) != 1+4*len(Metrics) {
+		t.Errorf("got %d rows, want %d", len(rows), 1+4*len(Metrics))
+	}
+
+	sw.Points = [][]float64{{-1}}
+	if _, err := sw.Run(); err == nil {
+		t.Error("got no error for a point that isn't a valid scenario")
+	}
+}
+
+func TestLoadSweep(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sweep.json")
+	spec := `{"params": [{"name": "increment", "min": 1, "max": 5, "round": 1}], "samples": 4, "seeds": 3}`
+	if err := ioutil.WriteFile(path, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sw, err := LoadSweep(path, smallScenario())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sw.Points) != 4 || sw.Seeds != 3 {
+		t.Errorf("got %d points and %d seeds, want 4 and 3", len(sw.Points), sw.Seeds)
+	}
+	for _, point := range sw.Points {
+		if point[0] != math.Round(point[0]) {
+			t.Errorf("got %v, want whole numbers", sw.Points)
+		}
+	}
+}
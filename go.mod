@@ -2,4 +2,7 @@ module github.com/robbrit/econerra
 
 go 1.15
 
-require golang.org/x/tools v0.0.0-20200913032122-97363e29fc9b // indirect
+require (
+	github.com/mattn/go-sqlite3 v1.14.6
+	golang.org/x/tools v0.0.0-20200913032122-97363e29fc9b // indirect
+)
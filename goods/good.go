@@ -1,5 +1,7 @@
 package goods
 
+import "fmt"
+
 // A Good is something that a human consumes to gain utility.
 //go:generate stringer -type=Good
 type Good uint8
@@ -12,3 +14,28 @@ const (
 )
 
 var AllGoods = []Good{Grain, Vegetables, Meat}
+
+// Parse finds the good with the given name, e.g. "Grain".
+func Parse(name string) (Good, error) {
+	for _, good := range []Good{Grain, Vegetables, Meat, Labour} {
+		if good.String() == name {
+			return good, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown good %q", name)
+}
+
+// MarshalText encodes a good as its name.
+func (g Good) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+// UnmarshalText decodes a good from its name.
+func (g *Good) UnmarshalText(text []byte) error {
+	good, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*g = good
+	return nil
+}
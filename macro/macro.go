@@ -0,0 +1,198 @@
+// Package macro computes economy-wide statistics from the state of the agents.
+package macro
+
+import (
+	"math"
+
+	"github.com/robbrit/econerra/agents"
+	"github.com/robbrit/econerra/goods"
+)
+
+// Stats are the macroeconomic indicators for a single cycle.
+type Stats struct {
+	// Value of all consumer goods sold this cycle.
+	NominalGDP float64 `json:"nominal_gdp"`
+	// Quantity of consumer goods sold this cycle, valued at base prices.
+	RealGDP float64 `json:"real_gdp"`
+	// Consumer price index, 100 at base prices.
+	CPI float64 `json:"cpi"`
+	// Change in the CPI since last cycle, as a fraction.
+	Inflation float64 `json:"inflation"`
+	// Fraction of workers that didn't find work this cycle.
+	UnemploymentRate float64 `json:"unemployment_rate"`
+	// Average wage paid to employed workers.
+	AverageWage float64 `json:"average_wage"`
+	// Average wage deflated by the CPI.
+	RealWage float64 `json:"real_wage"`
+	// Fraction of nominal GDP paid out in wages.
+	WageShare float64 `json:"wage_share"`
+	// How much the prices asked by firms making the same good differ: the
+	// standard deviation of their prices relative to the mean, averaged over
+	// the goods.
+	PriceDispersion float64 `json:"price_dispersion"`
+}
+
+// A Tracker computes the statistics cycle after cycle. It remembers the base
+// prices for the CPI and real GDP, which are the first prices observed for
+// each good.
+type Tracker struct {
+	base    map[goods.Good]float64
+	last    map[goods.Good]float64
+	lastCPI float64
+}
+
+// NewTracker creates a tracker that has not seen any prices yet.
+func NewTracker() *Tracker {
+	return &Tracker{
+		base: map[goods.Good]float64{},
+		last: map[goods.Good]float64{},
+	}
+}
+
+// TrackerState is what a tracker remembers from one cycle to the next, for
+// saving a simulation part way through.
+type TrackerState struct {
+	// The first and last average prices of each good that has been traded.
+	Base map[goods.Good]float64 `json:"base"`
+	Last map[goods.Good]float64 `json:"last"`
+	// The CPI of the last cycle, 0 before the first.
+	LastCPI float64 `json:"last_cpi"`
+}
+
+// State gets what the tracker remembers.
+func (t *Tracker) State() TrackerState {
+	st := TrackerState{
+		Base:    map[goods.Good]float64{},
+		Last:    map[goods.Good]float64{},
+		LastCPI: t.lastCPI,
+	}
+	for good, price := range t.base {
+		st.Base[good] = price
+	}
+	for good, price := range t.last {
+		st.Last[good] = price
+	}
+	return st
+}
+
+// RestoreTracker creates a tracker that remembers the same as when its state
+// was saved.
+func RestoreTracker(st TrackerState) *Tracker {
+	t := NewTracker()
+	for good, price := range st.Base {
+		t.base[good] = price
+	}
+	for good, price := range st.Last {
+		t.last[good] = price
+	}
+	t.lastCPI = st.LastCPI
+	return t
+}
+
+// Update computes the statistics for the cycle that just ended.
+func (t *Tracker) Update(p *agents.Parameters, firms []*agents.Firm, workers []*agents.Worker) Stats {
+	var s Stats
+
+	revenue := map[goods.Good]float64{}
+	sales := map[goods.Good]float64{}
+	prices := map[goods.Good][]float64{}
+	wageBill := 0.0
+	for _, f := range firms {
+		revenue[f.Good()] += f.Revenue().Float()
+		sales[f.Good()] += f.SalesMade().Float()
+		prices[f.Good()] = append(prices[f.Good()], f.Price().Float())
+		wageBill += f.WageBill().Float()
+	}
+
+	for _, good := range goods.AllGoods {
+		s.NominalGDP += revenue[good]
+		if sales[good] == 0 {
+			// Nothing traded, so keep the last known price.
+			continue
+		}
+		price := revenue[good] / sales[good]
+		t.last[good] = price
+		if _, ok := t.base[good]; !ok {
+			t.base[good] = price
+		}
+		s.RealGDP += sales[good] * t.base[good]
+	}
+
+	/*
+		The CPI is the cost of the workers' consumption basket relative to base prices. Since
+		workers' expenditure shares come from the CES share factors, those are the weights:
+
+			CPI = 100 * sum(w_i * p_i / base_i) / sum(w_i)
+			w_i = p.Goods[i].Share
+
+		Goods that haven't been traded yet are left out.
+	*/
+	weighted, weights := 0.0, 0.0
+	for _, good := range goods.AllGoods {
+		base, ok := t.base[good]
+		if !ok {
+			continue
+		}
+		weighted += p.Goods[good].Share * t.last[good] / base
+		weights += p.Goods[good].Share
+	}
+	s.CPI = 100
+	if weights > 0 {
+		s.CPI = 100 * weighted / weights
+	}
+	if t.lastCPI > 0 {
+		s.Inflation = s.CPI/t.lastCPI - 1
+	}
+	t.lastCPI = s.CPI
+
+	employed := 0
+	for _, w := range workers {
+		if !w.Unemployed() {
+			employed++
+		}
+	}
+	if len(workers) > 0 {
+		s.UnemploymentRate = float64(len(workers)-employed) / float64(len(workers))
+	}
+	if employed > 0 {
+		s.AverageWage = wageBill / float64(employed)
+	}
+	s.RealWage = s.AverageWage / (s.CPI / 100)
+	if s.NominalGDP > 0 {
+		s.WageShare = wageBill / s.NominalGDP
+	}
+	s.PriceDispersion = dispersion(prices)
+
+	return s
+}
+
+// dispersion gets the coefficient of variation of each good's prices,
+// averaged over the goods that have any.
+func dispersion(prices map[goods.Good][]float64) float64 {
+	total, n := 0.0, 0
+	for _, good := range goods.AllGoods {
+		ps := prices[good]
+		if len(ps) == 0 {
+			continue
+		}
+		mean := 0.0
+		for _, p := range ps {
+			mean += p
+		}
+		mean /= float64(len(ps))
+		variance := 0.0
+		for _, p := range ps {
+			variance += (p - mean) * (p - mean)
+		}
+		variance /= float64(len(ps))
+
+		if mean > 0 {
+			total += math.Sqrt(variance) / mean
+		}
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return total / float64(n)
+}
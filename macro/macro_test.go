@@ -0,0 +1,90 @@
+package macro
+
+import (
+	"math"
+	"testing"
+
+	"github.com/robbrit/econerra/agents"
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestTracker(t *testing.T) {
+	p := &agents.Parameters{Goods: map[goods.Good]agents.GoodParameters{
+		goods.Grain:      {Share: 3},
+		goods.Vegetables: {Share: 1},
+		goods.Meat:       {Share: 1},
+	}}
+	tr := NewTracker()
+
+	// One firm per good and four workers, three of which get hired.
+	newEconomy := func() ([]*agents.Firm, []*agents.Worker) {
+		firms := []*agents.Firm{
+			agents.NewFirm(goods.Grain, 10, 2, 1000),
+			agents.NewFirm(goods.Vegetables, 10, 2, 1000),
+		}
+		var workers []*agents.Worker
+		for i := 0; i < 4; i++ {
+			workers = append(workers, agents.NewWorker(10, 2, 0))
+		}
+		for i := 0; i < 3; i++ {
+			firms[0].OnFill(goods.Labour, market.Buy, 1, 10, 1)
+			workers[i].OnFill(goods.Labour, market.Sell, 2, 10, 1)
+		}
+		return firms, workers
+	}
+
+	// Cycle 0: grain at 2, vegetables at 4.
+	firms, workers := newEconomy()
+	firms[0].OnFill(goods.Grain, market.Sell, 1, 2, 10)
+	firms[1].OnFill(goods.Vegetables, market.Sell, 1, 4, 5)
+	s := tr.Update(p, firms, workers)
+	if s.NominalGDP != 40 || s.RealGDP != 40 || s.CPI != 100 || s.Inflation != 0 {
+		t.Errorf("cycle 0: got %+v", s)
+	}
+	if s.UnemploymentRate != 0.25 || s.AverageWage != 10 || s.RealWage != 10 {
+		t.Errorf("cycle 0: got %+v", s)
+	}
+	// 3 workers at 10 each out of 40 of sales, and every firm asks the same price.
+	if s.WageShare != 0.75 || s.PriceDispersion != 0 {
+		t.Errorf("cycle 0: got wage share %g and price dispersion %g, want 0.75 and 0", s.WageShare, s.PriceDispersion)
+	}
+
+	// Cycle 1: grain doubles to 4, vegetables aren't traded so keep their price.
+	firms, workers = newEconomy()
+	firms[0].OnFill(goods.Grain, market.Sell, 1, 4, 10)
+	s = tr.Update(p, firms, workers)
+	if s.NominalGDP != 40 || s.RealGDP != 20 {
+		t.Errorf("cycle 1: got GDP %g nominal, %g real; want 40 and 20", s.NominalGDP, s.RealGDP)
+	}
+	// Meat has never traded, so only grain and vegetables are in the basket.
+	if want := 100 * (3*2 + 1*1) / 4.0; !near(s.CPI, want) {
+		t.Errorf("cycle 1: got CPI %g, want %g", s.CPI, want)
+	}
+	if want := 0.75; !near(s.Inflation, want) {
+		t.Errorf("cycle 1: got inflation %g, want %g", s.Inflation, want)
+	}
+	if want := 10 / 1.75; !near(s.RealWage, want) {
+		t.Errorf("cycle 1: got real wage %g, want %g", s.RealWage, want)
+	}
+}
+
+func TestPriceDispersion(t *testing.T) {
+	p := &agents.Parameters{Goods: map[goods.Good]agents.GoodParameters{}}
+	firms := []*agents.Firm{
+		agents.NewFirm(goods.Grain, 10, 2, 1000),
+		agents.NewFirm(goods.Grain, 10, 6, 1000),
+		agents.NewFirm(goods.Meat, 10, 5, 1000),
+	}
+
+	// Grain prices are 4 +/- 2, and meat only has one price.
+	s := NewTracker().Update(p, firms, nil)
+	if want := (0.5 + 0) / 2; !near(s.PriceDispersion, want) {
+		t.Errorf("got price dispersion %g, want %g", s.PriceDispersion, want)
+	}
+	if s.WageShare != 0 {
+		t.Errorf("got wage share %g with no sales, want 0", s.WageShare)
+	}
+}
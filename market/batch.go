@@ -0,0 +1,72 @@
+package market
+
+// A batch holds the orders for markets that don't fill anything until they are
+// reset. Orders are kept in the order they arrived.
+//
+// Since every order in a batch only lasts until the market is reset anyway,
+// immediate-or-cancel orders are the same as limit orders.
+type batch struct {
+	bids   []*Order
+	offers []*Order
+	nextID OrderID
+}
+
+// add gives an order an ID and queues it up.
+func (b *batch) add(o *Order) OrderID {
+	b.nextID++
+	o.ID = b.nextID
+	switch o.Side {
+	case Buy:
+		b.bids = append(b.bids, o)
+	case Sell:
+		b.offers = append(b.offers, o)
+	}
+	return o.ID
+}
+
+// find looks for an open order, giving the queue that it's in and where.
+func (b *batch) find(id OrderID) (*[]*Order, int) {
+	for _, orders := range []*[]*Order{&b.bids, &b.offers} {
+		for i, o := range *orders {
+			if o.ID == id {
+				return orders, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// Cancel takes an order out of the batch.
+func (b *batch) Cancel(id OrderID) error {
+	orders, i := b.find(id)
+	if orders == nil {
+		return ErrUnknownOrder
+	}
+	*orders = append((*orders)[:i], (*orders)[i+1:]...)
+	return nil
+}
+
+// Amend changes an order in the batch. Lowering the size keeps the order where
+// it is, anything else sends it to the back of the queue.
+func (b *batch) Amend(id OrderID, price Price, size Size) error {
+	orders, i := b.find(id)
+	if orders == nil {
+		return ErrUnknownOrder
+	}
+	o := (*orders)[i]
+	if size == 0 {
+		return ErrZeroSize
+	}
+	if price <= 0 && o.Type != MarketOrder {
+		return ErrBadPrice
+	}
+
+	if price == o.Price && size <= o.Size {
+		o.Size = size
+		return nil
+	}
+	*orders = append(append((*orders)[:i], (*orders)[i+1:]...), o)
+	o.Price = price
+	o.Size = size
+	return nil
+}
@@ -0,0 +1,175 @@
+package market
+
+import (
+	"sort"
+
+	"github.com/robbrit/econerra/goods"
+)
+
+// A callAuction gathers all the orders posted during a period, and then clears
+// them all at once when the market is reset, at the single price that
+// maximizes the volume traded. Unlike the double auction, the order that
+// orders arrive in only matters for breaking ties between orders at the same
+// price.
+//
+// Market orders trade at whatever price the limit orders clear at, and
+// fill-or-kill orders aren't supported.
+type callAuction struct {
+	marketData
+	batch
+	lastHigh   Price
+	lastLow    Price
+	lastVolume Size
+	bid        Price
+	ask        Price
+	good       goods.Good
+}
+
+// NewCallAuction constructs a new periodic call auction for a given good.
+func NewCallAuction(good goods.Good) Market {
+	return &callAuction{good: good}
+}
+
+func (m *callAuction) Bid() Price       { return m.bid }
+func (m *callAuction) Ask() Price       { return m.ask }
+func (m *callAuction) High() Price      { return m.lastHigh }
+func (m *callAuction) Low() Price       { return m.lastLow }
+func (m *callAuction) Volume() Size     { return m.lastVolume }
+func (m *callAuction) Good() goods.Good { return m.good }
+
+// Post sends an order to the market. Nothing is filled until the market is
+// reset.
+func (m *callAuction) Post(o *Order) (OrderID, error) {
+	if err := m.check(o, o.Type != FillOrKill); err != nil {
+		return 0, err
+	}
+	defer verify(m.good, m.invariants)
+	return m.add(o), nil
+}
+
+// clearingPrice finds the price that maximizes the volume traded. If several
+// prices give the same volume, the one that leaves the smallest imbalance
+// between supply and demand wins, and after that the midpoint of the ones left.
+func (m *callAuction) clearingPrice() (Price, Size) {
+	var prices []Price
+	for _, orders := range [][]*Order{m.bids, m.offers} {
+		for _, o := range orders {
+			if o.Type != MarketOrder {
+				prices = append(prices, o.Price)
+			}
+		}
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
+
+	var best []Price
+	bestVolume, bestImbalance := Size(0), Size(0)
+	for i, p := range prices {
+		if i > 0 && prices[i-1] == p {
+			continue
+		}
+
+		demand, supply := Size(0), Size(0)
+		for _, o := range m.bids {
+			if o.limit() >= p {
+				demand = demand.Add(o.Size)
+			}
+		}
+		for _, o := range m.offers {
+			if o.limit() <= p {
+				supply = supply.Add(o.Size)
+			}
+		}
+
+		volume, imbalance := demand, supply-demand
+		if supply < demand {
+			volume, imbalance = supply, demand-supply
+		}
+		if volume == 0 {
+			continue
+		}
+
+		if volume > bestVolume || (volume == bestVolume && imbalance < bestImbalance) {
+			best = []Price{p}
+			bestVolume, bestImbalance = volume, imbalance
+		} else if volume == bestVolume && imbalance == bestImbalance {
+			best = append(best, p)
+		}
+	}
+
+	if len(best) == 0 {
+		return 0, 0
+	}
+	return best[0] + (best[len(best)-1]-best[0])/2, bestVolume
+}
+
+// match pairs up bids and offers in price priority, with earlier orders first
+// at the same price, until the volume has been traded.
+func (m *callAuction) match(price Price, volume Size) {
+	bids, offers := m.bids, m.offers
+	for volume > 0 {
+		buy, sell := bids[0], offers[0]
+
+		size := volume
+		if buy.Size < size {
+			size = buy.Size
+		}
+		if sell.Size < size {
+			size = sell.Size
+		}
+		m.execute(m.good, buy, sell, price, size)
+		volume -= size
+		buy.Size -= size
+		sell.Size -= size
+
+		if buy.Size == 0 {
+			bids = bids[1:]
+		}
+		if sell.Size == 0 {
+			offers = offers[1:]
+		}
+	}
+	m.bids, m.offers = bids, offers
+}
+
+func (m *callAuction) Reset() {
+	m.closed = true
+	defer func() { m.closed = false }()
+
+	sort.SliceStable(m.bids, func(i, j int) bool { return m.bids[i].limit() > m.bids[j].limit() })
+	sort.SliceStable(m.offers, func(i, j int) bool { return m.offers[i].limit() < m.offers[j].limit() })
+
+	price, volume := m.clearingPrice()
+	m.lastHigh = price
+	m.lastLow = price
+	m.lastVolume = volume
+	m.match(price, volume)
+
+	m.endPeriod(m.bids, m.offers)
+
+	// Clear out all the orders, sending unfilled notifications as needed.
+	for _, order := range m.bids {
+		order.Owner.OnUnfilled(m.good, Buy, order.ID, order.Size)
+	}
+	for _, order := range m.offers {
+		order.Owner.OnUnfilled(m.good, Sell, order.ID, order.Size)
+	}
+
+	// Market orders don't have a price to quote.
+	m.bid = 0
+	for _, order := range m.bids {
+		if order.Type != MarketOrder {
+			m.bid = order.Price
+			break
+		}
+	}
+	m.ask = 0
+	for _, order := range m.offers {
+		if order.Type != MarketOrder {
+			m.ask = order.Price
+			break
+		}
+	}
+
+	m.bids = nil
+	m.offers = nil
+}
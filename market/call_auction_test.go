@@ -0,0 +1,144 @@
+package market
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+)
+
+func TestCallAuction(t *testing.T) {
+	// Same situation as the double auction: one seller, three buyers - one buyer high, one
+	// medium, one low. Everything should trade at the seller's price.
+	b1 := &fakeAgent{}
+	b2 := &fakeAgent{}
+	b3 := &fakeAgent{}
+
+	s := &fakeAgent{}
+
+	m := NewCallAuction(goods.Labour)
+
+	m.Post(&Order{Price: 10, Size: 100, Side: Sell, Owner: s})
+	m.Post(&Order{Price: 12, Size: 10, Side: Buy, Owner: b1})
+	m.Post(&Order{Price: 10, Size: 200, Side: Buy, Owner: b2})
+	m.Post(&Order{Price: 8, Size: 1000, Side: Buy, Owner: b3})
+
+	if *b1 != (fakeAgent{}) || *s != (fakeAgent{}) {
+		t.Fatal("orders were filled before the auction cleared")
+	}
+
+	m.Reset()
+
+	for _, test := range []struct {
+		desc      string
+		agent     *fakeAgent
+		wantAgent *fakeAgent
+	}{
+		{
+			"high buy should get filled at 10",
+			b1,
+			&fakeAgent{10, 10, 1, Buy, 0, 0},
+		},
+		{
+			"mid buy should get partially filled",
+			b2,
+			&fakeAgent{10, 90, 1, Buy, 110, Buy},
+		},
+		{
+			"low buy should not get filled at all",
+			b3,
+			&fakeAgent{0, 0, 0, 0, 1000, Buy},
+		},
+		{
+			"sell should be filled all at once",
+			s,
+			&fakeAgent{10, 100, 2, Sell, 0, 0},
+		},
+	} {
+		if *test.agent != *test.wantAgent {
+			t.Errorf("%s: got %v, want %v", test.desc, test.agent, test.wantAgent)
+		}
+	}
+
+	if m.Volume() != 100 || m.High() != 10 || m.Low() != 10 {
+		t.Errorf("got volume %d, high %d, low %d; want 100, 10, 10", m.Volume(), m.High(), m.Low())
+	}
+	if m.Bid() != 10 || m.Ask() != 0 {
+		t.Errorf("got bid %d, ask %d; want 10, 0", m.Bid(), m.Ask())
+	}
+}
+
+func TestCallAuctionUniformPrice(t *testing.T) {
+	// Two sellers, one buyer that wants more than the cheap seller has. Both 7 and 9 clear
+	// 15 units, so the price is halfway between them and both sellers get it.
+	s1 := &fakeAgent{}
+	s2 := &fakeAgent{}
+	b := &fakeAgent{}
+
+	m := NewCallAuction(goods.Grain)
+	m.Post(&Order{Price: 9, Size: 15, Side: Buy, Owner: b})
+	m.Post(&Order{Price: 5, Size: 10, Side: Sell, Owner: s1})
+	m.Post(&Order{Price: 7, Size: 10, Side: Sell, Owner: s2})
+	m.Reset()
+
+	for _, test := range []struct {
+		desc      string
+		agent     *fakeAgent
+		wantAgent *fakeAgent
+	}{
+		{"buy gets filled completely", b, &fakeAgent{8, 15, 2, Buy, 0, 0}},
+		{"cheap sell gets filled completely", s1, &fakeAgent{8, 10, 1, Sell, 0, 0}},
+		{"expensive sell gets the rest", s2, &fakeAgent{8, 5, 1, Sell, 5, Sell}},
+	} {
+		if *test.agent != *test.wantAgent {
+			t.Errorf("%s: got %v, want %v", test.desc, test.agent, test.wantAgent)
+		}
+	}
+}
+
+func TestCallAuctionCancelAndAmend(t *testing.T) {
+	m := NewCallAuction(goods.Grain)
+	s := newOrderAgent()
+	b := newOrderAgent()
+
+	gone, _ := m.Post(&Order{Price: 5, Size: 10, Side: Sell, Owner: s})
+	sell, _ := m.Post(&Order{Price: 9, Size: 10, Side: Sell, Owner: s})
+	buy, _ := m.Post(&Order{Price: 8, Size: 10, Side: Buy, Owner: b})
+
+	if err := m.Cancel(gone); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if err := m.Amend(gone, 5, 10); err != ErrUnknownOrder {
+		t.Errorf("amending a cancelled order got %v, want %v", err, ErrUnknownOrder)
+	}
+	if err := m.Amend(sell, 8, 4); err != nil {
+		t.Fatalf("Amend: %v", err)
+	}
+	m.Reset()
+
+	if s.filled[gone] != 0 || s.unfilled[gone] != 0 {
+		t.Errorf("cancelled order got filled %d and unfilled %d, want nothing", s.filled[gone], s.unfilled[gone])
+	}
+	if s.filled[sell] != 4 || b.filled[buy] != 4 || b.unfilled[buy] != 6 {
+		t.Errorf("got seller %v, buyer %v and %v, want 4 traded and 6 left over", s.filled, b.filled, b.unfilled)
+	}
+}
+
+func TestCallAuctionMarketOrders(t *testing.T) {
+	m := NewCallAuction(goods.Grain)
+	b := &fakeAgent{}
+	s := &fakeAgent{}
+
+	m.Post(&Order{Size: 10, Side: Buy, Owner: b, Type: MarketOrder})
+	m.Post(&Order{Price: 7, Size: 4, Side: Sell, Owner: s})
+	if _, err := m.Post(&Order{Price: 7, Size: 4, Side: Sell, Owner: s, Type: FillOrKill}); err != ErrUnsupported {
+		t.Errorf("posting a FOK order got %v, want %v", err, ErrUnsupported)
+	}
+	m.Reset()
+
+	if want := (fakeAgent{7, 4, 1, Buy, 6, Buy}); *b != want {
+		t.Errorf("got buyer %v, want %v", *b, want)
+	}
+	if m.Bid() != 0 {
+		t.Errorf("got bid %d, want no bid for a market order", m.Bid())
+	}
+}
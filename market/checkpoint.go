@@ -0,0 +1,110 @@
+package market
+
+import (
+	"errors"
+
+	"github.com/robbrit/econerra/rng"
+)
+
+// ErrOpenOrders is returned when taking a snapshot of a market that has open
+// orders. Snapshots can only be taken between trading periods.
+var ErrOpenOrders = errors.New("market: market has open orders")
+
+// A Snapshot is the state of a market between trading periods: what agents can
+// see of the last period, and what the market needs to carry on the same way.
+type Snapshot struct {
+	Bid    Price `json:"bid"`
+	Ask    Price `json:"ask"`
+	High   Price `json:"high"`
+	Low    Price `json:"low"`
+	Volume Size  `json:"volume"`
+	// The last order ID given out, and the last place in the queue for
+	// markets that keep one.
+	LastID  OrderID `json:"last_id"`
+	LastSeq uint64  `json:"last_seq,omitempty"`
+	// Where the market's random numbers are up to, for markets that use them.
+	Rand *rng.State `json:"rand,omitempty"`
+}
+
+// A Checkpointer is a market that can be saved between trading periods, and
+// restored into a new market of the same kind.
+type Checkpointer interface {
+	// Snapshot gets the state of the market, or ErrOpenOrders if it isn't
+	// between trading periods.
+	Snapshot() (Snapshot, error)
+	// Restore puts a new market into the state of a snapshot.
+	Restore(Snapshot)
+}
+
+func (m *doubleAuction) Snapshot() (Snapshot, error) {
+	if len(m.orders) > 0 {
+		return Snapshot{}, ErrOpenOrders
+	}
+	return Snapshot{
+		Bid:     m.bid,
+		Ask:     m.ask,
+		High:    m.lastHigh,
+		Low:     m.lastLow,
+		Volume:  m.lastVolume,
+		LastID:  m.nextID,
+		LastSeq: m.seq,
+	}, nil
+}
+
+func (m *doubleAuction) Restore(s Snapshot) {
+	m.bid, m.ask = s.Bid, s.Ask
+	m.lastHigh, m.lastLow, m.lastVolume = s.High, s.Low, s.Volume
+	m.nextID, m.seq = s.LastID, s.LastSeq
+}
+
+// snapshot gets the state shared by the markets that fill orders in batches.
+func (b *batch) snapshot(bid, ask, high, low Price, volume Size, src *rng.Source) (Snapshot, error) {
+	if len(b.bids) > 0 || len(b.offers) > 0 {
+		return Snapshot{}, ErrOpenOrders
+	}
+	s := Snapshot{Bid: bid, Ask: ask, High: high, Low: low, Volume: volume, LastID: b.nextID}
+	if src != nil {
+		st := src.State()
+		s.Rand = &st
+	}
+	return s, nil
+}
+
+// restore puts back the state shared by the markets that fill orders in
+// batches.
+func (b *batch) restore(s Snapshot, src *rng.Source) {
+	b.nextID = s.LastID
+	if src != nil && s.Rand != nil {
+		src.Restore(*s.Rand)
+	}
+}
+
+func (m *callAuction) Snapshot() (Snapshot, error) {
+	return m.snapshot(m.bid, m.ask, m.lastHigh, m.lastLow, m.lastVolume, nil)
+}
+
+func (m *callAuction) Restore(s Snapshot) {
+	m.restore(s, nil)
+	m.bid, m.ask = s.Bid, s.Ask
+	m.lastHigh, m.lastLow, m.lastVolume = s.High, s.Low, s.Volume
+}
+
+func (m *postedPrice) Snapshot() (Snapshot, error) {
+	return m.snapshot(m.bid, m.ask, m.lastHigh, m.lastLow, m.lastVolume, m.src)
+}
+
+func (m *postedPrice) Restore(s Snapshot) {
+	m.restore(s, m.src)
+	m.bid, m.ask = s.Bid, s.Ask
+	m.lastHigh, m.lastLow, m.lastVolume = s.High, s.Low, s.Volume
+}
+
+func (m *matchingMarket) Snapshot() (Snapshot, error) {
+	return m.snapshot(m.bid, m.ask, m.lastHigh, m.lastLow, m.lastVolume, m.src)
+}
+
+func (m *matchingMarket) Restore(s Snapshot) {
+	m.restore(s, m.src)
+	m.bid, m.ask = s.Bid, s.Ask
+	m.lastHigh, m.lastLow, m.lastVolume = s.High, s.Low, s.Volume
+}
@@ -0,0 +1,261 @@
+package market
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// MaxPrecision is the most decimal places that prices and sizes can have.
+const MaxPrecision = 9
+
+// Prices and sizes are fixed-point decimals: a Price or Size stores a whole
+// number of the smallest unit, which is 10^-precision. With the default
+// precision of 0 they are whole numbers.
+var (
+	precision = 0
+	scale     = uint64(1)
+)
+
+// SetPrecision sets how many decimal places prices and sizes have, for the
+// whole program. It changes what every Price and Size means, so it has to be
+// called before any are made. Simulations check that it hasn't been changed
+// from their scenario's precision.
+func SetPrecision(places int) error {
+	if places < 0 || places > MaxPrecision {
+		return fmt.Errorf("market: precision must be between 0 and %d, got %d", MaxPrecision, places)
+	}
+	precision = places
+	scale = 1
+	for i := 0; i < places; i++ {
+		scale *= 10
+	}
+	return nil
+}
+
+// Precision gets how many decimal places prices and sizes have.
+func Precision() int { return precision }
+
+// NewPrice converts a number to a price, rounding down to the precision.
+// Negative numbers become 0.
+func NewPrice(f float64) Price { return Price(fromFloat(f, math.Floor)) }
+
+// NewSize converts a number to a size, rounding down to the precision.
+// Negative numbers become 0.
+func NewSize(f float64) Size { return Size(fromFloat(f, math.Floor)) }
+
+// CeilSize converts a number to a size, rounding up to the precision.
+func CeilSize(f float64) Size { return Size(fromFloat(f, math.Ceil)) }
+
+// Units gives a size of n whole units.
+func Units(n int) Size { return Size(uint64(n) * scale) }
+
+func fromFloat(f float64, round func(float64) float64) uint64 {
+	// Rounding the scaled number on its own can be thrown off by floating point
+	// error, e.g. 0.29 * 100 is just under 29.
+	v := f * float64(scale)
+	if r := math.Round(v); math.Abs(v-r) < 1e-9*math.Max(1, math.Abs(v)) {
+		v = r
+	}
+	v = round(v)
+	switch {
+	case v <= 0 || math.IsNaN(v):
+		return 0
+	case v >= math.MaxUint64:
+		return math.MaxUint64
+	}
+	return uint64(v)
+}
+
+// Float converts a price to a floating point number.
+func (p Price) Float() float64 { return float64(p) / float64(scale) }
+
+// Float converts a size to a floating point number.
+func (s Size) Float() float64 { return float64(s) / float64(scale) }
+
+// Units gets how many whole units are in a size.
+func (s Size) Units() int { return int(uint64(s) / scale) }
+
+// ErrOverflow is returned by checked arithmetic when the result doesn't fit in
+// a Price or Size, either because it's too big or because it's below zero.
+var ErrOverflow = errors.New("market: arithmetic overflow")
+
+// Add adds q to p, stopping at the largest price instead of wrapping around.
+func (p Price) Add(q Price) Price { return Price(add(uint64(p), uint64(q))) }
+
+// Add adds t to s, stopping at the largest size instead of wrapping around.
+func (s Size) Add(t Size) Size { return Size(add(uint64(s), uint64(t))) }
+
+func add(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return sum
+}
+
+// Sub subtracts q from p, stopping at zero instead of wrapping around.
+func (p Price) Sub(q Price) Price {
+	if q > p {
+		return 0
+	}
+	return p - q
+}
+
+// Sub subtracts t from s, stopping at zero instead of wrapping around.
+func (s Size) Sub(t Size) Size {
+	if t > s {
+		return 0
+	}
+	return s - t
+}
+
+// Mul gets the cost of size s at price p, rounded down to the precision.
+func (p Price) Mul(s Size) Price { return Price(mulDiv(uint64(p), uint64(s), scale)) }
+
+// Div gets how much can be bought with p at price q, rounded down to the
+// precision. Nothing can be bought at a price of zero.
+func (p Price) Div(q Price) Size {
+	if q == 0 {
+		return 0
+	}
+	return Size(mulDiv(uint64(p), scale, uint64(q)))
+}
+
+// CheckedAdd adds q to p, or gives ErrOverflow if the result doesn't fit.
+func (p Price) CheckedAdd(q Price) (Price, error) {
+	sum, err := checkedAdd(uint64(p), uint64(q))
+	return Price(sum), err
+}
+
+// CheckedAdd adds t to s, or gives ErrOverflow if the result doesn't fit.
+func (s Size) CheckedAdd(t Size) (Size, error) {
+	sum, err := checkedAdd(uint64(s), uint64(t))
+	return Size(sum), err
+}
+
+// CheckedSub subtracts q from p, or gives ErrOverflow if q is bigger than p.
+func (p Price) CheckedSub(q Price) (Price, error) {
+	if q > p {
+		return 0, ErrOverflow
+	}
+	return p - q, nil
+}
+
+// CheckedSub subtracts t from s, or gives ErrOverflow if t is bigger than s.
+func (s Size) CheckedSub(t Size) (Size, error) {
+	if t > s {
+		return 0, ErrOverflow
+	}
+	return s - t, nil
+}
+
+// CheckedMul gets the cost of size s at price p like Mul, or gives ErrOverflow
+// if it doesn't fit.
+func (p Price) CheckedMul(s Size) (Price, error) {
+	if hi, _ := bits.Mul64(uint64(p), uint64(s)); hi >= scale {
+		return 0, ErrOverflow
+	}
+	return p.Mul(s), nil
+}
+
+func checkedAdd(a, b uint64) (uint64, error) {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return 0, ErrOverflow
+	}
+	return sum, nil
+}
+
+// mulDiv works out a * b / c without overflowing in the middle, saturating if
+// the result doesn't fit.
+func mulDiv(a, b, c uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	if hi >= c {
+		return math.MaxUint64
+	}
+	q, _ := bits.Div64(hi, lo, c)
+	return q
+}
+
+func (p Price) String() string { return format(uint64(p)) }
+func (s Size) String() string  { return format(uint64(s)) }
+
+func format(v uint64) string {
+	whole := strconv.FormatUint(v/scale, 10)
+	if precision == 0 {
+		return whole
+	}
+	frac := fmt.Sprintf("%0*d", precision, v%scale)
+	frac = strings.TrimRight(frac, "0")
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+// parse reads a decimal number, which can't have more decimal places than the
+// precision.
+func parse(s string) (uint64, error) {
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f < 0 {
+			return 0, fmt.Errorf("market: bad decimal %q", s)
+		}
+		return fromFloat(f, math.Round), nil
+	}
+
+	parts := strings.SplitN(s, ".", 2)
+	whole, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("market: bad decimal %q", s)
+	}
+	var frac uint64
+	if len(parts) == 2 {
+		digits := strings.TrimRight(parts[1], "0")
+		if len(digits) > precision {
+			return 0, fmt.Errorf("market: %q has more than %d decimal places", s, precision)
+		}
+		if digits != "" {
+			if frac, err = strconv.ParseUint(digits, 10, 64); err != nil {
+				return 0, fmt.Errorf("market: bad decimal %q", s)
+			}
+			for i := len(digits); i < precision; i++ {
+				frac *= 10
+			}
+		}
+	}
+	if whole > (math.MaxUint64-frac)/scale {
+		return 0, fmt.Errorf("market: %q is too big", s)
+	}
+	return whole*scale + frac, nil
+}
+
+// MarshalJSON writes a price as a JSON number.
+func (p Price) MarshalJSON() ([]byte, error) { return []byte(p.String()), nil }
+
+// UnmarshalJSON reads a price from a JSON number.
+func (p *Price) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	v, err := parse(string(b))
+	*p = Price(v)
+	return err
+}
+
+// MarshalJSON writes a size as a JSON number.
+func (s Size) MarshalJSON() ([]byte, error) { return []byte(s.String()), nil }
+
+// UnmarshalJSON reads a size from a JSON number.
+func (s *Size) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	v, err := parse(string(b))
+	*s = Size(v)
+	return err
+}
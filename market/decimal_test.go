@@ -0,0 +1,132 @@
+package market
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestDecimal(t *testing.T) {
+	if err := SetPrecision(2); err != nil {
+		t.Fatal(err)
+	}
+	defer SetPrecision(0)
+
+	if got := NewPrice(0.29); got != 29 {
+		t.Errorf("NewPrice(0.29) = %d hundredths, want 29", got)
+	}
+	if got := NewSize(1.999); got != 199 {
+		t.Errorf("NewSize(1.999) = %d hundredths, want 199", got)
+	}
+	if got := CeilSize(1.001); got != 101 {
+		t.Errorf("CeilSize(1.001) = %d hundredths, want 101", got)
+	}
+	if got := NewPrice(-3); got != 0 {
+		t.Errorf("NewPrice(-3) = %d, want 0", got)
+	}
+	if got := Units(3); got != 300 || got.Units() != 3 {
+		t.Errorf("Units(3) = %d hundredths, want 300", got)
+	}
+
+	// 2.50 * 0.33 = 0.825, rounded down.
+	if got := NewPrice(2.5).Mul(NewSize(0.33)); got != 82 {
+		t.Errorf("2.5 * 0.33 = %s, want 0.82", got)
+	}
+	// 1.00 / 0.30 = 3.333...
+	if got := NewPrice(1).Div(NewPrice(0.3)); got != 333 {
+		t.Errorf("1 / 0.3 = %s, want 3.33", got)
+	}
+	if got := NewPrice(1).Div(0); got != 0 {
+		t.Errorf("1 / 0 = %s, want 0", got)
+	}
+	if got := Price(math.MaxUint64).Mul(Units(2)); got != math.MaxUint64 {
+		t.Errorf("overflowing multiplication got %d, want it to saturate", got)
+	}
+
+	if got := NewPrice(1).Sub(NewPrice(2)); got != 0 {
+		t.Errorf("1 - 2 = %s, want 0", got)
+	}
+	if got := NewSize(5).Sub(NewSize(2.5)); got != 250 {
+		t.Errorf("5 - 2.5 = %s, want 2.5", got)
+	}
+
+	for _, test := range []struct {
+		in   Price
+		want string
+	}{
+		{0, "0"},
+		{5, "0.05"},
+		{250, "2.5"},
+		{10000, "100"},
+	} {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("%d hundredths printed as %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	if err := SetPrecision(2); err != nil {
+		t.Fatal(err)
+	}
+	defer SetPrecision(0)
+
+	var v struct {
+		Price Price
+		Size  Size
+	}
+	if err := json.Unmarshal([]byte(`{"Price": 2.5, "Size": 1e1}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Price != 250 || v.Size != 1000 {
+		t.Errorf("got %d and %d hundredths, want 250 and 1000", v.Price, v.Size)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Price":2.5,"Size":10}`; string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+
+	for _, bad := range []string{`{"Price": 2.555}`, `{"Price": -1}`, `{"Price": "2"}`} {
+		if err := json.Unmarshal([]byte(bad), &v); err == nil {
+			t.Errorf("got no error for %s", bad)
+		}
+	}
+
+	if err := SetPrecision(MaxPrecision + 1); err == nil {
+		t.Error("got no error for too much precision")
+	}
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+	if got := Price(math.MaxUint64 - 1).Add(2); got != math.MaxUint64 {
+		t.Errorf("overflowing addition got %d, want it to saturate", got)
+	}
+	if got := Size(2).Add(3); got != 5 {
+		t.Errorf("2 + 3 = %d, want 5", got)
+	}
+
+	if _, err := Price(math.MaxUint64).CheckedAdd(1); err != ErrOverflow {
+		t.Errorf("overflowing price addition got %v, want ErrOverflow", err)
+	}
+	if _, err := Size(math.MaxUint64).CheckedAdd(1); err != ErrOverflow {
+		t.Errorf("overflowing size addition got %v, want ErrOverflow", err)
+	}
+	if got, err := Size(2).CheckedAdd(3); got != 5 || err != nil {
+		t.Errorf("2 + 3 = %d, %v, want 5", got, err)
+	}
+	if _, err := Price(1).CheckedSub(2); err != ErrOverflow {
+		t.Errorf("1 - 2 got %v, want ErrOverflow", err)
+	}
+	if got, err := Size(3).CheckedSub(3); got != 0 || err != nil {
+		t.Errorf("3 - 3 = %d, %v, want 0", got, err)
+	}
+	if _, err := Price(math.MaxUint64).CheckedMul(Units(2)); err != ErrOverflow {
+		t.Errorf("overflowing multiplication got %v, want ErrOverflow", err)
+	}
+	if got, err := Price(4).CheckedMul(Units(3)); got != 12 || err != nil {
+		t.Errorf("4 * 3 = %d, %v, want 12", got, err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 )
 
 type doubleAuction struct {
+	marketData
 	bids       orderMaxHeap
 	offers     orderMinHeap
 	lastHigh   Price
@@ -18,9 +19,15 @@ type doubleAuction struct {
 	bid        Price
 	ask        Price
 	good       goods.Good
+	nextID     OrderID
+	seq        uint64
+	proRata    bool
+	// All the orders resting in the book, by ID.
+	orders map[OrderID]*Order
 }
 
-// NewDoubleAuction constructs a new market for a given good.
+// NewDoubleAuction constructs a new market for a given good. Orders at the
+// same price are filled in the order they were posted.
 func NewDoubleAuction(good goods.Good) Market {
 	m := &doubleAuction{
 		good: good,
@@ -29,6 +36,18 @@ func NewDoubleAuction(good goods.Good) Market {
 	return m
 }
 
+// NewProRataAuction constructs a double auction where an order that can't fill
+// everything at the best price shares its size among all the orders at that
+// price, in proportion to their sizes, instead of filling the earliest first.
+func NewProRataAuction(good goods.Good) Market {
+	m := &doubleAuction{
+		good:    good,
+		proRata: true,
+	}
+	m.Reset()
+	return m
+}
+
 func (m *doubleAuction) Bid() Price       { return m.bid }
 func (m *doubleAuction) Ask() Price       { return m.ask }
 func (m *doubleAuction) High() Price      { return m.lastHigh }
@@ -37,73 +56,215 @@ func (m *doubleAuction) Volume() Size     { return m.lastVolume }
 func (m *doubleAuction) Good() goods.Good { return m.good }
 
 // Post sends an order to the market. If this order results in a fill,
-// the owner(s) will be notified. If not, the order will remain open in
-// the market.
-func (m *doubleAuction) Post(o *Order) {
-	if o.Size == 0 {
-		return
+// the owner(s) will be notified. If not, a limit order will remain open in
+// the market, and any other type of order is cancelled.
+func (m *doubleAuction) Post(o *Order) (OrderID, error) {
+	if err := m.check(o, true); err != nil {
+		return 0, err
 	}
-	if o.Price <= 0 {
-		return
+
+	defer verify(m.good, m.invariants)
+
+	m.nextID++
+	o.ID = m.nextID
+	m.stamp(o)
+	if o.Type == FillOrKill && m.available(o) < o.Size {
+		o.Owner.OnUnfilled(m.good, o.Side, o.ID, o.Size)
+		return o.ID, nil
+	}
+	m.match(o)
+	return o.ID, nil
+}
+
+// Cancel takes an order out of the book.
+func (m *doubleAuction) Cancel(id OrderID) error {
+	o, ok := m.orders[id]
+	if !ok {
+		return ErrUnknownOrder
+	}
+	defer verify(m.good, m.invariants)
+	m.remove(o)
+	return nil
+}
+
+// Amend changes an order in the book. Lowering the size keeps the order where
+// it is, anything else is the same as cancelling the order and posting it again
+// with the same ID.
+func (m *doubleAuction) Amend(id OrderID, price Price, size Size) error {
+	o, ok := m.orders[id]
+	if !ok {
+		return ErrUnknownOrder
 	}
+	if size == 0 {
+		return ErrZeroSize
+	}
+	if price <= 0 {
+		return ErrBadPrice
+	}
+	defer verify(m.good, m.invariants)
+	if price == o.Price && size <= o.Size {
+		o.Size = size
+		return nil
+	}
+
+	m.remove(o)
+	o.Price = price
+	o.Size = size
+	m.stamp(o)
+	m.match(o)
+	return nil
+}
+
+// stamp gives an order its place in the queue behind every order already
+// posted.
+func (m *doubleAuction) stamp(o *Order) {
+	m.seq++
+	o.seq = m.seq
+}
 
+func (m *doubleAuction) rest(o *Order) {
+	m.orders[o.ID] = o
 	switch o.Side {
 	case Buy:
-		if len(m.offers) == 0 || o.Price < m.offers[0].Price {
-			heap.Push(&m.bids, o)
-			return
-		}
+		heap.Push(&m.bids, o)
+	case Sell:
+		heap.Push(&m.offers, o)
+	}
+}
 
-		// Pop sell orders off the heap until we have filled the entire amount.
-		size := o.Size
-		for len(m.offers) > 0 && o.Price >= m.offers[0].Price && size > 0 {
-			if m.offers[0].Size <= size {
-				sell := heap.Pop(&m.offers).(*Order)
-				m.handleFill(o, sell, sell.Price, sell.Size)
-				size -= sell.Size
-			} else {
-				sell := m.offers[0]
-				m.handleFill(o, sell, sell.Price, size)
-				m.offers[0].Size -= size
-				size = 0
-			}
-		}
+func (m *doubleAuction) remove(o *Order) {
+	delete(m.orders, o.ID)
+	switch o.Side {
+	case Buy:
+		heap.Remove(&m.bids, o.index)
+	case Sell:
+		heap.Remove(&m.offers, o.index)
+	}
+}
+
+// opposite gets the side of the book that an order trades against, and which
+// prices in it the order is willing to trade at.
+func (m *doubleAuction) opposite(o *Order) (orderHeap, func(Price) bool) {
+	limit := o.limit()
+	if o.Side == Buy {
+		return &m.offers, func(p Price) bool { return p <= limit }
+	}
+	return &m.bids, func(p Price) bool { return p >= limit }
+}
 
-		if size > 0 {
-			o.Size = size
-			heap.Push(&m.bids, o)
+// available gets how much of an order could be filled straight away, up to the
+// size of the order.
+func (m *doubleAuction) available(o *Order) Size {
+	book, crosses := m.opposite(o)
+	size := Size(0)
+	for i := 0; i < book.Len() && size < o.Size; i++ {
+		// The heap isn't sorted, so look at every order in it.
+		if resting := book.At(i); crosses(resting.Price) {
+			size = size.Add(resting.Size)
 		}
-	case Sell:
-		if len(m.bids) == 0 || o.Price > m.bids[0].Price {
-			heap.Push(&m.offers, o)
-			return
+	}
+	if size > o.Size {
+		return o.Size
+	}
+	return size
+}
+
+// match fills an order against the book as far as possible, and rests
+// whatever is left.
+func (m *doubleAuction) match(o *Order) {
+	book, crosses := m.opposite(o)
+
+	size := o.Size
+	for book.Len() > 0 && crosses(book.Peek().Price) && size > 0 {
+		if m.proRata {
+			size = m.fillProRata(o, book, size)
+		} else {
+			size = m.fillFIFO(o, book, size)
 		}
+	}
+
+	if size == 0 {
+		return
+	}
+	o.Size = size
+	if o.Type == LimitOrder {
+		m.rest(o)
+	} else {
+		o.Owner.OnUnfilled(m.good, o.Side, o.ID, size)
+	}
+}
+
+// fillFIFO fills up to size of an order against the first order in the book,
+// giving back how much is left to fill.
+func (m *doubleAuction) fillFIFO(o *Order, book orderHeap, size Size) Size {
+	resting := book.Peek()
+	if resting.Size > size {
+		m.trade(o, resting, size)
+		resting.Size -= size
+		return 0
+	}
+
+	heap.Pop(book)
+	delete(m.orders, resting.ID)
+	m.trade(o, resting, resting.Size)
+	return size - resting.Size
+}
+
+// fillProRata fills up to size of an order against all the orders at the best
+// price in the book at once. If there isn't enough to go around, each resting
+// order gets a share in proportion to its size, and the units lost to rounding
+// go to the earliest orders.
+func (m *doubleAuction) fillProRata(o *Order, book orderHeap, size Size) Size {
+	price := book.Peek().Price
+	var level []*Order
+	total := Size(0)
+	for book.Len() > 0 && book.Peek().Price == price {
+		resting := heap.Pop(book).(*Order)
+		level = append(level, resting)
+		total = total.Add(resting.Size)
+	}
 
-		// Pop buy orders off the heap until we have filled the entire amount.
-		size := o.Size
-		for len(m.bids) > 0 && o.Price <= m.bids[0].Price && size > 0 {
-			if m.bids[0].Size <= size {
-				buy := heap.Pop(&m.bids).(*Order)
-				m.handleFill(buy, o, buy.Price, buy.Size)
-				size -= buy.Size
-			} else {
-				buy := m.bids[0]
-				m.handleFill(buy, o, buy.Price, size)
-				m.bids[0].Size -= size
-				size = 0
-			}
+	shares := make([]Size, len(level))
+	left := size
+	for i, resting := range level {
+		if total <= size {
+			shares[i] = resting.Size
+		} else {
+			shares[i] = Size(mulDiv(uint64(size), uint64(resting.Size), uint64(total)))
 		}
+		left -= shares[i]
+	}
+	for i := 0; i < len(level) && left > 0 && total > size; i++ {
+		shares[i]++
+		left--
+	}
 
-		if size > 0 {
-			o.Size = size
-			heap.Push(&m.offers, o)
+	for i, resting := range level {
+		if shares[i] > 0 {
+			m.trade(o, resting, shares[i])
+		}
+		if shares[i] == resting.Size {
+			delete(m.orders, resting.ID)
+			continue
 		}
+		resting.Size -= shares[i]
+		heap.Push(book, resting)
+	}
+	return left
+}
+
+// trade fills an incoming order against a resting one, at the resting order's
+// price.
+func (m *doubleAuction) trade(o, resting *Order, size Size) {
+	if o.Side == Buy {
+		m.handleFill(o, resting, resting.Price, size)
+	} else {
+		m.handleFill(resting, o, resting.Price, size)
 	}
 }
 
 func (m *doubleAuction) handleFill(buy, sell *Order, price Price, size Size) {
-	buy.Owner.OnFill(m.good, Buy, price, size)
-	sell.Owner.OnFill(m.good, Sell, price, size)
+	m.execute(m.good, buy, sell, price, size)
 
 	if price > m.high {
 		m.high = price
@@ -111,10 +272,13 @@ func (m *doubleAuction) handleFill(buy, sell *Order, price Price, size Size) {
 	if m.low == 0 || price < m.low {
 		m.low = price
 	}
-	m.volume += size
+	m.volume = m.volume.Add(size)
 }
 
 func (m *doubleAuction) Reset() {
+	m.closed = true
+	defer func() { m.closed = false }()
+
 	m.lastLow = m.low
 	m.lastHigh = m.high
 	m.lastVolume = m.volume
@@ -122,12 +286,14 @@ func (m *doubleAuction) Reset() {
 	m.low = 0
 	m.volume = 0
 
+	m.endPeriod(m.bids, m.offers)
+
 	// Clear out all the orders, sending unfilled notifications as needed.
 	for _, order := range m.bids {
-		order.Owner.OnUnfilled(m.good, Buy, order.Size)
+		order.Owner.OnUnfilled(m.good, Buy, order.ID, order.Size)
 	}
 	for _, order := range m.offers {
-		order.Owner.OnUnfilled(m.good, Sell, order.Size)
+		order.Owner.OnUnfilled(m.good, Sell, order.ID, order.Size)
 	}
 
 	if len(m.bids) > 0 {
@@ -143,6 +309,7 @@ func (m *doubleAuction) Reset() {
 
 	m.bids = orderMaxHeap{}
 	m.offers = orderMinHeap{}
+	m.orders = map[OrderID]*Order{}
 	heap.Init(&m.bids)
 	heap.Init(&m.offers)
 }
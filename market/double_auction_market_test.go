@@ -6,22 +6,26 @@ import (
 	"github.com/robbrit/econerra/goods"
 )
 
+// A fakeAgent adds up its fills, and remembers the price and side of the last
+// one and what was left unfilled.
 type fakeAgent struct {
 	fillPrice Price
 	fillSize  Size
+	fills     int
 	fillSide  Side
 
 	unfilledSize Size
 	unfilledSide Side
 }
 
-func (fa *fakeAgent) OnFill(g goods.Good, s Side, p Price, q Size) {
+func (fa *fakeAgent) OnFill(g goods.Good, s Side, id OrderID, p Price, q Size) {
 	fa.fillPrice = p
-	fa.fillSize = q
+	fa.fillSize += q
+	fa.fills++
 	fa.fillSide = s
 }
 
-func (fa *fakeAgent) OnUnfilled(g goods.Good, s Side, q Size) {
+func (fa *fakeAgent) OnUnfilled(g goods.Good, s Side, id OrderID, q Size) {
 	fa.unfilledSize = q
 	fa.unfilledSide = s
 }
@@ -36,10 +40,10 @@ func TestMarket(t *testing.T) {
 
 	m := NewDoubleAuction(goods.Labour)
 
-	m.Post(&Order{10, 100, Sell, s})
-	m.Post(&Order{12, 10, Buy, b1})
-	m.Post(&Order{10, 200, Buy, b2})
-	m.Post(&Order{8, 1000, Buy, b3})
+	m.Post(&Order{Price: 10, Size: 100, Side: Sell, Owner: s})
+	m.Post(&Order{Price: 12, Size: 10, Side: Buy, Owner: b1})
+	m.Post(&Order{Price: 10, Size: 200, Side: Buy, Owner: b2})
+	m.Post(&Order{Price: 8, Size: 1000, Side: Buy, Owner: b3})
 
 	m.Reset()
 
@@ -51,22 +55,22 @@ func TestMarket(t *testing.T) {
 		{
 			"high buy should get filled at 10",
 			b1,
-			&fakeAgent{10, 10, Buy, 0, 0},
+			&fakeAgent{10, 10, 1, Buy, 0, 0},
 		},
 		{
 			"mid buy should get partially filled",
 			b2,
-			&fakeAgent{10, 90, Buy, 110, Buy},
+			&fakeAgent{10, 90, 1, Buy, 110, Buy},
 		},
 		{
 			"low buy should not get filled at all",
 			b3,
-			&fakeAgent{0, 0, 0, 1000, Buy},
+			&fakeAgent{0, 0, 0, 0, 1000, Buy},
 		},
 		{
-			"sell should have latest fill values",
+			"sell should be filled completely",
 			s,
-			&fakeAgent{10, 90, Sell, 0, 0},
+			&fakeAgent{10, 100, 2, Sell, 0, 0},
 		},
 	} {
 		if *test.agent != *test.wantAgent {
@@ -74,3 +78,264 @@ func TestMarket(t *testing.T) {
 		}
 	}
 }
+
+// orderAgent records which of its orders were filled and left unfilled.
+type orderAgent struct {
+	filled   map[OrderID]Size
+	unfilled map[OrderID]Size
+}
+
+func newOrderAgent() *orderAgent {
+	return &orderAgent{filled: map[OrderID]Size{}, unfilled: map[OrderID]Size{}}
+}
+
+func (oa *orderAgent) OnFill(g goods.Good, s Side, id OrderID, p Price, q Size) {
+	oa.filled[id] += q
+}
+
+func (oa *orderAgent) OnUnfilled(g goods.Good, s Side, id OrderID, q Size) {
+	oa.unfilled[id] += q
+}
+
+func TestOrderIDs(t *testing.T) {
+	m := NewDoubleAuction(goods.Grain)
+	a := newOrderAgent()
+
+	cheap, _ := m.Post(&Order{Price: 10, Size: 5, Side: Sell, Owner: a})
+	pricey, _ := m.Post(&Order{Price: 12, Size: 5, Side: Sell, Owner: a})
+	if cheap == 0 || pricey == 0 || cheap == pricey {
+		t.Fatalf("got IDs %d and %d, want distinct non-zero IDs", cheap, pricey)
+	}
+	if id, err := m.Post(&Order{Price: 10, Size: 0, Side: Sell, Owner: a}); id != 0 || err != ErrZeroSize {
+		t.Errorf("empty order got ID %d and %v, want 0 and %v", id, err, ErrZeroSize)
+	}
+
+	m.Post(&Order{Price: 11, Size: 8, Side: Buy, Owner: newOrderAgent()})
+	m.Reset()
+
+	if a.filled[cheap] != 5 || a.filled[pricey] != 0 {
+		t.Errorf("got fills %v, want 5 for order %d only", a.filled, cheap)
+	}
+	if a.unfilled[pricey] != 5 || a.unfilled[cheap] != 0 {
+		t.Errorf("got unfilled %v, want 5 for order %d only", a.unfilled, pricey)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	m := NewDoubleAuction(goods.Grain)
+	s := newOrderAgent()
+	b := newOrderAgent()
+
+	id, _ := m.Post(&Order{Price: 10, Size: 5, Side: Sell, Owner: s})
+	if err := m.Cancel(id); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if err := m.Cancel(id); err != ErrUnknownOrder {
+		t.Errorf("cancelling twice got %v, want %v", err, ErrUnknownOrder)
+	}
+
+	m.Post(&Order{Price: 10, Size: 5, Side: Buy, Owner: b})
+	m.Reset()
+
+	if len(s.filled) > 0 || len(s.unfilled) > 0 {
+		t.Errorf("cancelled order got fills %v and unfilled %v, want none", s.filled, s.unfilled)
+	}
+	if m.Volume() != 0 || m.Ask() != 0 {
+		t.Errorf("got volume %d and ask %d, want an empty book", m.Volume(), m.Ask())
+	}
+}
+
+func TestAmend(t *testing.T) {
+	m := NewDoubleAuction(goods.Grain)
+	s := newOrderAgent()
+	b := newOrderAgent()
+
+	sell, _ := m.Post(&Order{Price: 12, Size: 5, Side: Sell, Owner: s})
+	buy, _ := m.Post(&Order{Price: 10, Size: 5, Side: Buy, Owner: b})
+
+	if err := m.Amend(sell, 12, 0); err != ErrZeroSize {
+		t.Errorf("amending to zero got %v, want %v", err, ErrZeroSize)
+	}
+	if err := m.Amend(99, 12, 1); err != ErrUnknownOrder {
+		t.Errorf("amending an unknown order got %v, want %v", err, ErrUnknownOrder)
+	}
+
+	// Lowering the sell price crosses the bid.
+	if err := m.Amend(sell, 10, 3); err != nil {
+		t.Fatalf("Amend: %v", err)
+	}
+	if s.filled[sell] != 3 || b.filled[buy] != 3 {
+		t.Errorf("got fills %v and %v, want 3 each", s.filled, b.filled)
+	}
+	if err := m.Amend(sell, 10, 1); err != ErrUnknownOrder {
+		t.Errorf("amending a filled order got %v, want %v", err, ErrUnknownOrder)
+	}
+
+	// Shrinking the rest of the buy order.
+	if err := m.Amend(buy, 10, 1); err != nil {
+		t.Fatalf("Amend: %v", err)
+	}
+	m.Reset()
+
+	if b.unfilled[buy] != 1 {
+		t.Errorf("got unfilled %v, want 1 for order %d", b.unfilled, buy)
+	}
+	if m.Volume() != 3 || m.Bid() != 10 {
+		t.Errorf("got volume %d and bid %d, want 3 and 10", m.Volume(), m.Bid())
+	}
+}
+
+func TestTimePriority(t *testing.T) {
+	// Many sells at the same price, so that heap order would scramble them
+	// without time priority.
+	m := NewDoubleAuction(goods.Grain)
+	s := newOrderAgent()
+	var ids []OrderID
+	for i := 0; i < 20; i++ {
+		id, _ := m.Post(&Order{Price: 10, Size: 2, Side: Sell, Owner: s})
+		ids = append(ids, id)
+	}
+	// A better price jumps the queue, a worse one waits at the back.
+	better, _ := m.Post(&Order{Price: 9, Size: 1, Side: Sell, Owner: s})
+	worse, _ := m.Post(&Order{Price: 11, Size: 100, Side: Sell, Owner: s})
+
+	// Amending to a smaller size keeps the order's place, and moving the price
+	// sends it to the back.
+	if err := m.Amend(ids[0], 10, 1); err != nil {
+		t.Fatalf("Amend: %v", err)
+	}
+	if err := m.Amend(ids[1], 11, 2); err != nil {
+		t.Fatalf("Amend: %v", err)
+	}
+	if err := m.Amend(ids[1], 10, 2); err != nil {
+		t.Fatalf("Amend: %v", err)
+	}
+
+	b := newOrderAgent()
+	for i := 0; i < 12; i++ {
+		m.Post(&Order{Price: 10, Size: 2, Side: Buy, Owner: b})
+	}
+
+	want := map[OrderID]Size{better: 1, ids[0]: 1}
+	left := Size(24) - 2
+	for _, id := range ids[2:] {
+		if left < 2 {
+			want[id] = left
+			break
+		}
+		want[id] = 2
+		left -= 2
+	}
+	for id, size := range want {
+		if s.filled[id] != size {
+			t.Errorf("order %d got filled %d, want %d", id, s.filled[id], size)
+		}
+	}
+	for _, id := range []OrderID{ids[1], worse} {
+		if s.filled[id] != 0 {
+			t.Errorf("order %d got filled %d, want 0", id, s.filled[id])
+		}
+	}
+}
+
+func TestProRata(t *testing.T) {
+	m := NewProRataAuction(goods.Grain)
+	s := newOrderAgent()
+
+	small, _ := m.Post(&Order{Price: 10, Size: 10, Side: Sell, Owner: s})
+	big, _ := m.Post(&Order{Price: 10, Size: 30, Side: Sell, Owner: s})
+	odd, _ := m.Post(&Order{Price: 10, Size: 1, Side: Sell, Owner: s})
+	worse, _ := m.Post(&Order{Price: 11, Size: 10, Side: Sell, Owner: s})
+
+	// 21 out of 41 at the best price: the shares come to 5, 15 and 0, and the
+	// unit left over from rounding goes to the earliest order.
+	b := newOrderAgent()
+	buy, _ := m.Post(&Order{Price: 11, Size: 21, Side: Buy, Owner: b})
+
+	for id, size := range map[OrderID]Size{small: 6, big: 15, odd: 0, worse: 0} {
+		if s.filled[id] != size {
+			t.Errorf("order %d got filled %d, want %d", id, s.filled[id], size)
+		}
+	}
+	if b.filled[buy] != 21 {
+		t.Errorf("buy got filled %d, want 21", b.filled[buy])
+	}
+
+	// Enough to clear out the best price fills everything there and moves on.
+	m.Post(&Order{Price: 11, Size: 25, Side: Buy, Owner: b})
+	m.Reset()
+	for id, size := range map[OrderID]Size{small: 10, big: 30, odd: 1, worse: 5} {
+		if s.filled[id] != size {
+			t.Errorf("order %d got filled %d in total, want %d", id, s.filled[id], size)
+		}
+	}
+	if s.unfilled[worse] != 5 {
+		t.Errorf("got unfilled %d, want 5", s.unfilled[worse])
+	}
+}
+
+func TestOrderTypes(t *testing.T) {
+	book := func() (Market, *orderAgent) {
+		m := NewDoubleAuction(goods.Grain)
+		s := newOrderAgent()
+		m.Post(&Order{Price: 10, Size: 5, Side: Sell, Owner: s})
+		m.Post(&Order{Price: 12, Size: 5, Side: Sell, Owner: s})
+		return m, s
+	}
+
+	for _, test := range []struct {
+		desc         string
+		order        Order
+		wantFilled   Size
+		wantUnfilled Size
+		wantAsk      Price
+	}{
+		{"market order takes any price", Order{Size: 8, Type: MarketOrder}, 8, 0, 12},
+		{"market order cancels what's left", Order{Size: 15, Type: MarketOrder}, 10, 5, 0},
+		{"IOC fills up to its limit and cancels the rest", Order{Price: 11, Size: 8, Type: ImmediateOrCancel}, 5, 3, 12},
+		{"FOK fills completely", Order{Price: 12, Size: 8, Type: FillOrKill}, 8, 0, 12},
+		{"FOK that can't fill completely doesn't fill at all", Order{Price: 11, Size: 8, Type: FillOrKill}, 0, 8, 10},
+		{"limit order waits", Order{Price: 11, Size: 8}, 5, 0, 12},
+	} {
+		m, _ := book()
+		b := newOrderAgent()
+		o := test.order
+		o.Side = Buy
+		o.Owner = b
+		id, err := m.Post(&o)
+		if err != nil {
+			t.Errorf("%s: order was rejected: %v", test.desc, err)
+			continue
+		}
+
+		// Anything cancelled is reported before the market is reset.
+		if b.filled[id] != test.wantFilled || b.unfilled[id] != test.wantUnfilled {
+			t.Errorf("%s: got filled %d and unfilled %d, want %d and %d",
+				test.desc, b.filled[id], b.unfilled[id], test.wantFilled, test.wantUnfilled)
+		}
+		if err := m.Cancel(id); (err == nil) != (test.order.Type == LimitOrder) {
+			t.Errorf("%s: cancelling got %v, want only limit orders to wait in the market", test.desc, err)
+		}
+		m.Reset()
+		if m.Ask() != test.wantAsk {
+			t.Errorf("%s: got ask %d, want %d", test.desc, m.Ask(), test.wantAsk)
+		}
+	}
+}
+
+func TestMarketSell(t *testing.T) {
+	m := NewDoubleAuction(goods.Grain)
+	b := newOrderAgent()
+	high, _ := m.Post(&Order{Price: 12, Size: 3, Side: Buy, Owner: b})
+	low, _ := m.Post(&Order{Price: 1, Size: 3, Side: Buy, Owner: b})
+
+	s := &fakeAgent{}
+	m.Post(&Order{Size: 4, Side: Sell, Owner: s, Type: MarketOrder})
+
+	if b.filled[high] != 3 || b.filled[low] != 1 {
+		t.Errorf("got fills %v, want the best bids first", b.filled)
+	}
+	if want := (fakeAgent{1, 4, 2, Sell, 0, 0}); *s != want {
+		t.Errorf("got %v, want %v", *s, want)
+	}
+}
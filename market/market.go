@@ -1,17 +1,47 @@
 package market
 
-import "github.com/robbrit/econerra/goods"
+import (
+	"errors"
+	"math"
+
+	"github.com/robbrit/econerra/goods"
+)
 
 // A Side represents the side that an order is on (buy vs. sell)
+//
 //go:generate stringer -type=Side
 type Side uint8
 
-// A Price is how much it costs to buy a good.
-type Price uint32
+// A Price is how much it costs to buy a good. Prices are fixed-point decimals,
+// see SetPrecision.
+type Price uint64
+
+// An OrderID identifies an order within a single market. Zero is never a
+// valid ID.
+type OrderID uint64
 
-// A Size is a quantity of a good.
-// TODO(rob): What if it's possible to buy/sell fractional amounts of a good?
-type Size uint32
+// A Size is a quantity of a good. Like prices, sizes are fixed-point decimals,
+// so goods can be bought and sold in fractional amounts.
+type Size uint64
+
+// An OrderType is how an order behaves when it can't be filled straight away.
+type OrderType uint8
+
+const (
+	// LimitOrder is an order to trade at a price or better. Whatever isn't
+	// filled waits in the market until the market is reset.
+	LimitOrder OrderType = iota
+	// MarketOrder is an order to trade at whatever price the other side asks.
+	// The order's price is ignored, and whatever can't be filled straight away
+	// is cancelled.
+	MarketOrder
+	// ImmediateOrCancel is a limit order where whatever can't be filled
+	// straight away is cancelled instead of waiting in the market.
+	ImmediateOrCancel
+	// FillOrKill is a limit order that is either filled completely straight
+	// away, or cancelled.
+	FillOrKill
+)
 
 const (
 	// Buy is an order to buy things.
@@ -20,10 +50,31 @@ const (
 	Sell
 )
 
+// ErrUnknownOrder is returned when cancelling or amending an order that isn't
+// open, e.g. because it has already been filled.
+var ErrUnknownOrder = errors.New("market: unknown order")
+
+// Reasons that a market rejects an order. Amending an order to have no size is
+// also rejected with ErrZeroSize, use Cancel instead.
+var (
+	ErrZeroSize    = errors.New("market: order size must be positive")
+	ErrBadPrice    = errors.New("market: limit order price must be positive")
+	ErrUnknownSide = errors.New("market: unknown order side")
+	ErrNoOwner     = errors.New("market: order has no owner")
+	ErrClosed      = errors.New("market: market is closed")
+	ErrUnsupported = errors.New("market: order type not supported by this market")
+)
+
 // Market represents a market for buying and selling goods.
 type Market interface {
-	// Post an order to this market.
-	Post(*Order)
+	// Post an order to this market, giving it an ID. If the market rejects the
+	// order, the error says why and the order has no ID.
+	Post(*Order) (OrderID, error)
+	// Cancel an open order. The owner is not notified.
+	Cancel(OrderID) error
+	// Amend the price and size of an open order. Changing the price or raising
+	// the size may cause the order to be filled.
+	Amend(id OrderID, price Price, size Size) error
 	// Reset the market.
 	Reset()
 	// Get the highest price for unfilled buy orders.
@@ -36,6 +87,13 @@ type Market interface {
 	Low() Price
 	// Get the volume of goods traded on this market in the last trading period.
 	Volume() Size
+	// Get the trades made in the last trading period.
+	Trades() Tape
+	// Get the orders that were still open at the end of the last trading
+	// period, i.e. the ones that couldn't be matched.
+	Depth() Depth
+	// Get how many orders were rejected in the last trading period.
+	Rejections() Rejections
 	// Gets the good bought/sold in this market.
 	Good() goods.Good
 }
@@ -44,9 +102,32 @@ type Market interface {
 // market events.
 type MarketAgent interface {
 	// OnFill is triggered when an order is filled.
-	OnFill(goods.Good, Side, Price, Size)
-	// OnUnfilled is called when the market is reset and order has not been filled.
-	OnUnfilled(goods.Good, Side, Size)
+	OnFill(goods.Good, Side, OrderID, Price, Size)
+	// OnUnfilled is called when the market is reset and order has not been
+	// filled, or straight away for the part of an order that was cancelled
+	// because it couldn't be filled immediately.
+	OnUnfilled(goods.Good, Side, OrderID, Size)
+}
+
+// A Matcher is a MarketAgent that also wants to know who it traded with, for
+// example to set up a relationship that lasts beyond the trade. Markets check
+// whether the owners of orders are Matchers when filling them.
+type Matcher interface {
+	// OnMatch is triggered along with OnFill, with the owner of the other order.
+	OnMatch(good goods.Good, side Side, id OrderID, counterparty MarketAgent, price Price, size Size)
+}
+
+// fill notifies the owners of two orders that they traded with each other.
+func fill(good goods.Good, buy, sell *Order, price Price, size Size) {
+	buy.Owner.OnFill(good, Buy, buy.ID, price, size)
+	sell.Owner.OnFill(good, Sell, sell.ID, price, size)
+
+	if m, ok := buy.Owner.(Matcher); ok {
+		m.OnMatch(good, Buy, buy.ID, sell.Owner, price, size)
+	}
+	if m, ok := sell.Owner.(Matcher); ok {
+		m.OnMatch(good, Sell, sell.ID, buy.Owner, price, size)
+	}
 }
 
 // An Order is an order to trade something in the market for a given price.
@@ -55,4 +136,25 @@ type Order struct {
 	Size  Size
 	Side  Side
 	Owner MarketAgent
+	// Limit orders by default.
+	Type OrderType
+	// Set by the market when the order is posted.
+	ID OrderID
+
+	// Where the order is in the heap, if it's in one.
+	index int
+	// When the order was queued, for time priority between orders at the same
+	// price.
+	seq uint64
+}
+
+// limit gets the worst price that an order will trade at.
+func (o *Order) limit() Price {
+	if o.Type != MarketOrder {
+		return o.Price
+	}
+	if o.Side == Buy {
+		return math.MaxUint64
+	}
+	return 0
 }
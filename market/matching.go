@@ -0,0 +1,148 @@
+package market
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/rng"
+)
+
+// A matchingMarket is a decentralised search market in the style of
+// Diamond-Mortensen-Pissarides. Buy orders are vacancies and sell orders are
+// job seekers. Instead of an order book, when the market is reset the number
+// of meetings between seekers and vacancies comes from a matching function:
+//
+//	M = efficiency * U^elasticity * V^(1 - elasticity)
+//
+// where U is the number of seekers and V the number of vacancies. Meetings
+// pair up random seekers with random vacancies, and turn into a match if the
+// vacancy's wage is at least the seeker's asking wage. The wage is halfway
+// between the two, i.e. Nash bargaining with equal bargaining power. Since
+// the wage needs both sides' prices, only limit and immediate-or-cancel orders
+// are supported.
+type matchingMarket struct {
+	marketData
+	// Bids are vacancies, offers are job seekers.
+	batch
+	efficiency float64
+	elasticity float64
+	src        *rng.Source
+	rand       *rand.Rand
+	lastHigh   Price
+	lastLow    Price
+	lastVolume Size
+	bid        Price
+	ask        Price
+	good       goods.Good
+}
+
+// NewMatching constructs a new search market for a given good, using random
+// numbers from src to decide who meets who.
+func NewMatching(good goods.Good, efficiency, elasticity float64, src *rng.Source) Market {
+	return &matchingMarket{
+		efficiency: efficiency,
+		elasticity: elasticity,
+		src:        src,
+		rand:       rand.New(src),
+		good:       good,
+	}
+}
+
+func (m *matchingMarket) Bid() Price       { return m.bid }
+func (m *matchingMarket) Ask() Price       { return m.ask }
+func (m *matchingMarket) High() Price      { return m.lastHigh }
+func (m *matchingMarket) Low() Price       { return m.lastLow }
+func (m *matchingMarket) Volume() Size     { return m.lastVolume }
+func (m *matchingMarket) Good() goods.Good { return m.good }
+
+// Post sends an order to the market. Nothing is matched until the market is
+// reset.
+func (m *matchingMarket) Post(o *Order) (OrderID, error) {
+	if err := m.check(o, o.Type != MarketOrder && o.Type != FillOrKill); err != nil {
+		return 0, err
+	}
+	defer verify(m.good, m.invariants)
+	return m.add(o), nil
+}
+
+// units lists each order once for every whole unit of its size, so that
+// drawing from it at random is weighted by size. Fractions of a unit are left
+// out, and never get matched.
+func units(orders []*Order) []*Order {
+	var us []*Order
+	for _, o := range orders {
+		for i := 0; i < o.Size.Units(); i++ {
+			us = append(us, o)
+		}
+	}
+	return us
+}
+
+func (m *matchingMarket) Reset() {
+	m.closed = true
+	defer func() { m.closed = false }()
+
+	seekers := units(m.offers)
+	vacancies := units(m.bids)
+
+	meetings := 0
+	if len(seekers) > 0 && len(vacancies) > 0 {
+		u, v := float64(len(seekers)), float64(len(vacancies))
+		meetings = int(math.Floor(m.efficiency * math.Pow(u, m.elasticity) * math.Pow(v, 1-m.elasticity)))
+		meetings = int(math.Min(float64(meetings), math.Min(u, v)))
+	}
+
+	m.rand.Shuffle(len(seekers), func(i, j int) { seekers[i], seekers[j] = seekers[j], seekers[i] })
+	m.rand.Shuffle(len(vacancies), func(i, j int) { vacancies[i], vacancies[j] = vacancies[j], vacancies[i] })
+
+	m.lastHigh = 0
+	m.lastLow = 0
+	m.lastVolume = 0
+	for i := 0; i < meetings; i++ {
+		seeker, vacancy := seekers[i], vacancies[i]
+		if vacancy.Price < seeker.Price {
+			continue
+		}
+
+		wage := seeker.Price + (vacancy.Price-seeker.Price)/2
+		m.execute(m.good, vacancy, seeker, wage, Units(1))
+		vacancy.Size -= Units(1)
+		seeker.Size -= Units(1)
+
+		if wage > m.lastHigh {
+			m.lastHigh = wage
+		}
+		if m.lastLow == 0 || wage < m.lastLow {
+			m.lastLow = wage
+		}
+		m.lastVolume = m.lastVolume.Add(Units(1))
+	}
+
+	m.endPeriod(m.bids, m.offers)
+
+	// Clear out all the orders, sending unfilled notifications as needed.
+	m.bid = 0
+	for _, order := range m.bids {
+		if order.Size == 0 {
+			continue
+		}
+		order.Owner.OnUnfilled(m.good, Buy, order.ID, order.Size)
+		if order.Price > m.bid {
+			m.bid = order.Price
+		}
+	}
+	m.ask = 0
+	for _, order := range m.offers {
+		if order.Size == 0 {
+			continue
+		}
+		order.Owner.OnUnfilled(m.good, Sell, order.ID, order.Size)
+		if m.ask == 0 || order.Price < m.ask {
+			m.ask = order.Price
+		}
+	}
+
+	m.bids = nil
+	m.offers = nil
+}
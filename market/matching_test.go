@@ -0,0 +1,62 @@
+package market
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/rng"
+)
+
+// matchingAgent records who it was matched with.
+type matchingAgent struct {
+	fakeAgent
+	counterparty MarketAgent
+}
+
+func (ma *matchingAgent) OnMatch(g goods.Good, s Side, id OrderID, c MarketAgent, p Price, q Size) {
+	ma.counterparty = c
+}
+
+func TestMatching(t *testing.T) {
+	// With an efficiency of 1, everyone meets when there are as many seekers as vacancies.
+	firm := &matchingAgent{}
+	cheap := &matchingAgent{}
+	pricey := &matchingAgent{}
+
+	m := NewMatching(goods.Labour, 1, 0.5, rng.NewSource(1))
+	m.Post(&Order{Price: 10, Size: 2, Side: Buy, Owner: firm})
+	m.Post(&Order{Price: 6, Size: 1, Side: Sell, Owner: cheap})
+	m.Post(&Order{Price: 12, Size: 1, Side: Sell, Owner: pricey})
+	m.Reset()
+
+	if want := (fakeAgent{8, 1, 1, Sell, 0, 0}); cheap.fakeAgent != want {
+		t.Errorf("cheap seeker: got %v, want %v", cheap.fakeAgent, want)
+	}
+	if cheap.counterparty != firm || firm.counterparty != cheap {
+		t.Error("cheap seeker and firm were not told about each other")
+	}
+	if want := (fakeAgent{0, 0, 0, 0, 1, Sell}); pricey.fakeAgent != want {
+		t.Errorf("seeker asking more than the vacancy pays: got %v, want %v", pricey.fakeAgent, want)
+	}
+	if want := (fakeAgent{8, 1, 1, Buy, 1, Buy}); firm.fakeAgent != want {
+		t.Errorf("firm: got %v, want %v", firm.fakeAgent, want)
+	}
+	if m.Volume() != 1 || m.Bid() != 10 || m.Ask() != 12 {
+		t.Errorf("got volume %d, bid %d, ask %d; want 1, 10, 12", m.Volume(), m.Bid(), m.Ask())
+	}
+}
+
+func TestMatchingFunction(t *testing.T) {
+	// 100 seekers and 25 vacancies with efficiency 0.5 and elasticity 0.5 gives
+	// 0.5 * sqrt(100 * 25) = 25 meetings, all of which should match.
+	m := NewMatching(goods.Labour, 0.5, 0.5, rng.NewSource(1))
+	m.Post(&Order{Price: 10, Size: 25, Side: Buy, Owner: &fakeAgent{}})
+	for i := 0; i < 100; i++ {
+		m.Post(&Order{Price: 5, Size: 1, Side: Sell, Owner: &fakeAgent{}})
+	}
+	m.Reset()
+
+	if m.Volume() != 25 {
+		t.Errorf("got %d matches, want 25", m.Volume())
+	}
+}
@@ -1,5 +1,16 @@
 package market
 
+import "container/heap"
+
+// An orderHeap is one side of an order book, best price first. Orders at the
+// same price are in the order they were queued.
+type orderHeap interface {
+	heap.Interface
+	Peek() *Order
+	// At gets the order at a position in the heap.
+	At(int) *Order
+}
+
 type orderMinHeap []*Order
 type orderMaxHeap []*Order
 
@@ -7,21 +18,37 @@ func (h orderMinHeap) Len() int { return len(h) }
 func (h orderMaxHeap) Len() int { return len(h) }
 
 func (h orderMinHeap) Less(i, j int) bool {
-	return h[i].Price < h[j].Price
+	if h[i].Price != h[j].Price {
+		return h[i].Price < h[j].Price
+	}
+	return h[i].seq < h[j].seq
 }
 func (h orderMaxHeap) Less(i, j int) bool {
-	return h[i].Price > h[j].Price
+	if h[i].Price != h[j].Price {
+		return h[i].Price > h[j].Price
+	}
+	return h[i].seq < h[j].seq
 }
 
-func (h orderMinHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
-func (h orderMaxHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h orderMinHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h orderMaxHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
 
 func (h *orderMinHeap) Push(x interface{}) {
 	o := x.(*Order)
+	o.index = len(*h)
 	*h = append(*h, o)
 }
 func (h *orderMaxHeap) Push(x interface{}) {
 	o := x.(*Order)
+	o.index = len(*h)
 	*h = append(*h, o)
 }
 
@@ -29,6 +56,7 @@ func (h *orderMinHeap) Pop() interface{} {
 	arr := *h
 	n := len(arr)
 	o := arr[n-1]
+	o.index = -1
 	*h = arr[0 : n-1]
 	return o
 }
@@ -36,9 +64,13 @@ func (h *orderMaxHeap) Pop() interface{} {
 	arr := *h
 	n := len(arr)
 	o := arr[n-1]
+	o.index = -1
 	*h = arr[0 : n-1]
 	return o
 }
 
 func (h orderMinHeap) Peek() *Order { return h[0] }
 func (h orderMaxHeap) Peek() *Order { return h[0] }
+
+func (h orderMinHeap) At(i int) *Order { return h[i] }
+func (h orderMaxHeap) At(i int) *Order { return h[i] }
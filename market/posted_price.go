@@ -0,0 +1,152 @@
+package market
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/rng"
+)
+
+// A postedPrice market works like a retail market: sellers post a price and
+// how much they have in stock, and buyers shop around. Each buyer only visits
+// a small random sample of the sellers, and buys from the cheapest of those
+// that still has stock, as long as the price is no more than the buyer's limit.
+//
+// Shopping happens when the market is reset, in the order that buyers posted,
+// so that every seller has had a chance to post first.
+//
+// A market buy order buys from the cheapest seller visited whatever the price.
+// Sellers must post a price, and fill-or-kill orders aren't supported.
+type postedPrice struct {
+	marketData
+	// Bids are shoppers, offers are posted prices.
+	batch
+	sampleSize int
+	src        *rng.Source
+	rand       *rand.Rand
+	lastHigh   Price
+	lastLow    Price
+	lastVolume Size
+	bid        Price
+	ask        Price
+	good       goods.Good
+}
+
+// NewPostedPrice constructs a new posted price market for a given good, where
+// each buyer visits sampleSize sellers chosen using random numbers from src.
+func NewPostedPrice(good goods.Good, sampleSize int, src *rng.Source) Market {
+	return &postedPrice{
+		sampleSize: sampleSize,
+		src:        src,
+		rand:       rand.New(src),
+		good:       good,
+	}
+}
+
+func (m *postedPrice) Bid() Price       { return m.bid }
+func (m *postedPrice) Ask() Price       { return m.ask }
+func (m *postedPrice) High() Price      { return m.lastHigh }
+func (m *postedPrice) Low() Price       { return m.lastLow }
+func (m *postedPrice) Volume() Size     { return m.lastVolume }
+func (m *postedPrice) Good() goods.Good { return m.good }
+
+// Post sends an order to the market. Sell orders are posted prices, buy orders
+// are shoppers. Nothing is filled until the market is reset.
+func (m *postedPrice) Post(o *Order) (OrderID, error) {
+	if err := m.check(o, o.Type != FillOrKill && (o.Type != MarketOrder || o.Side == Buy)); err != nil {
+		return 0, err
+	}
+	defer verify(m.good, m.invariants)
+	return m.add(o), nil
+}
+
+// sample picks which sellers a buyer visits, cheapest first.
+func (m *postedPrice) sample() []*Order {
+	n := m.sampleSize
+	if n > len(m.offers) {
+		n = len(m.offers)
+	}
+
+	visited := make([]*Order, 0, n)
+	for _, i := range m.rand.Perm(len(m.offers))[:n] {
+		visited = append(visited, m.offers[i])
+	}
+	sort.SliceStable(visited, func(i, j int) bool { return visited[i].Price < visited[j].Price })
+	return visited
+}
+
+func (m *postedPrice) shop(buy *Order) (high, low Price, volume Size) {
+	for _, sell := range m.sample() {
+		if sell.Price > buy.limit() || buy.Size == 0 {
+			break
+		}
+		if sell.Size == 0 {
+			// Out of stock.
+			continue
+		}
+
+		size := sell.Size
+		if size > buy.Size {
+			size = buy.Size
+		}
+		m.execute(m.good, buy, sell, sell.Price, size)
+		buy.Size -= size
+		sell.Size -= size
+
+		if sell.Price > high {
+			high = sell.Price
+		}
+		if low == 0 || sell.Price < low {
+			low = sell.Price
+		}
+		volume = volume.Add(size)
+	}
+	return high, low, volume
+}
+
+func (m *postedPrice) Reset() {
+	m.closed = true
+	defer func() { m.closed = false }()
+
+	m.lastHigh = 0
+	m.lastLow = 0
+	m.lastVolume = 0
+	for _, buy := range m.bids {
+		high, low, volume := m.shop(buy)
+		if high > m.lastHigh {
+			m.lastHigh = high
+		}
+		if low > 0 && (m.lastLow == 0 || low < m.lastLow) {
+			m.lastLow = low
+		}
+		m.lastVolume = m.lastVolume.Add(volume)
+	}
+
+	m.endPeriod(m.bids, m.offers)
+
+	// Clear out all the orders, sending unfilled notifications as needed.
+	m.bid = 0
+	for _, order := range m.bids {
+		if order.Size == 0 {
+			continue
+		}
+		order.Owner.OnUnfilled(m.good, Buy, order.ID, order.Size)
+		if order.Price > m.bid {
+			m.bid = order.Price
+		}
+	}
+	m.ask = 0
+	for _, order := range m.offers {
+		if order.Size == 0 {
+			continue
+		}
+		order.Owner.OnUnfilled(m.good, Sell, order.ID, order.Size)
+		if m.ask == 0 || order.Price < m.ask {
+			m.ask = order.Price
+		}
+	}
+
+	m.bids = nil
+	m.offers = nil
+}
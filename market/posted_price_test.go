@@ -0,0 +1,76 @@
+package market
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/rng"
+)
+
+func TestPostedPrice(t *testing.T) {
+	// The buyer visits every seller, and should buy from the cheapest first even though it
+	// posted before any of them.
+	cheap := &fakeAgent{}
+	mid := &fakeAgent{}
+	expensive := &fakeAgent{}
+	b := &fakeAgent{}
+
+	m := NewPostedPrice(goods.Grain, 3, rng.NewSource(1))
+	m.Post(&Order{Price: 6, Size: 15, Side: Buy, Owner: b})
+	m.Post(&Order{Price: 7, Size: 100, Side: Sell, Owner: expensive})
+	m.Post(&Order{Price: 4, Size: 10, Side: Sell, Owner: cheap})
+	m.Post(&Order{Price: 5, Size: 10, Side: Sell, Owner: mid})
+	m.Reset()
+
+	for _, test := range []struct {
+		desc      string
+		agent     *fakeAgent
+		wantAgent *fakeAgent
+	}{
+		{"buy is filled by the two cheapest sellers", b, &fakeAgent{5, 15, 2, Buy, 0, 0}},
+		{"cheapest seller sells out", cheap, &fakeAgent{4, 10, 1, Sell, 0, 0}},
+		{"next seller sells the rest", mid, &fakeAgent{5, 5, 1, Sell, 5, Sell}},
+		{"seller above the buyer's limit sells nothing", expensive, &fakeAgent{0, 0, 0, 0, 100, Sell}},
+	} {
+		if *test.agent != *test.wantAgent {
+			t.Errorf("%s: got %v, want %v", test.desc, test.agent, test.wantAgent)
+		}
+	}
+
+	if m.Volume() != 15 || m.Low() != 4 || m.High() != 5 {
+		t.Errorf("got volume %d, low %d, high %d; want 15, 4, 5", m.Volume(), m.Low(), m.High())
+	}
+	if m.Bid() != 0 || m.Ask() != 5 {
+		t.Errorf("got bid %d, ask %d; want 0, 5", m.Bid(), m.Ask())
+	}
+}
+
+func TestPostedPriceLimitedSearch(t *testing.T) {
+	// With a sample of one, each buyer only sees a single seller, so many buyers should
+	// spread their purchases over both sellers even though one is cheaper.
+	cheap := &countingAgent{}
+	expensive := &countingAgent{}
+
+	m := NewPostedPrice(goods.Grain, 1, rng.NewSource(1))
+	m.Post(&Order{Price: 1, Size: 1000, Side: Sell, Owner: cheap})
+	m.Post(&Order{Price: 2, Size: 1000, Side: Sell, Owner: expensive})
+	for i := 0; i < 100; i++ {
+		m.Post(&Order{Price: 2, Size: 1, Side: Buy, Owner: &countingAgent{}})
+	}
+	m.Reset()
+
+	if cheap.filled == 0 || expensive.filled == 0 {
+		t.Errorf("cheap seller sold %d and expensive seller sold %d, want both to sell", cheap.filled, expensive.filled)
+	}
+	if cheap.filled+expensive.filled != 100 {
+		t.Errorf("sold %d in total, want 100", cheap.filled+expensive.filled)
+	}
+}
+
+// countingAgent adds up all the fills it gets.
+type countingAgent struct {
+	filled Size
+}
+
+func (ca *countingAgent) OnFill(g goods.Good, s Side, id OrderID, p Price, q Size) { ca.filled += q }
+func (ca *countingAgent) OnUnfilled(g goods.Good, s Side, id OrderID, q Size)      {}
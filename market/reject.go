@@ -0,0 +1,59 @@
+package market
+
+// Rejections counts the orders that a market rejected, by reason.
+type Rejections struct {
+	ZeroSize    int `json:"zero_size"`
+	BadPrice    int `json:"bad_price"`
+	UnknownSide int `json:"unknown_side"`
+	NoOwner     int `json:"no_owner"`
+	Closed      int `json:"closed"`
+	Unsupported int `json:"unsupported"`
+}
+
+// Total gets the number of rejected orders.
+func (r Rejections) Total() int {
+	return r.ZeroSize + r.BadPrice + r.UnknownSide + r.NoOwner + r.Closed + r.Unsupported
+}
+
+func (r *Rejections) count(err error) {
+	switch err {
+	case ErrZeroSize:
+		r.ZeroSize++
+	case ErrBadPrice:
+		r.BadPrice++
+	case ErrUnknownSide:
+		r.UnknownSide++
+	case ErrNoOwner:
+		r.NoOwner++
+	case ErrClosed:
+		r.Closed++
+	case ErrUnsupported:
+		r.Unsupported++
+	}
+}
+
+// check decides whether a market takes an order, counting it if not. The
+// market says whether it supports the order's type.
+func (d *marketData) check(o *Order, supported bool) error {
+	var err error
+	switch {
+	case d.closed:
+		err = ErrClosed
+	case o.Owner == nil:
+		err = ErrNoOwner
+	case o.Side != Buy && o.Side != Sell:
+		err = ErrUnknownSide
+	case o.Type > FillOrKill || !supported:
+		err = ErrUnsupported
+	case o.Size == 0:
+		err = ErrZeroSize
+	case o.Price <= 0 && o.Type != MarketOrder:
+		err = ErrBadPrice
+	}
+	if err != nil {
+		d.rejections.count(err)
+	}
+	return err
+}
+
+func (d *marketData) Rejections() Rejections { return d.lastRejections }
@@ -0,0 +1,69 @@
+package market
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/rng"
+)
+
+func TestRejections(t *testing.T) {
+	a := &fakeAgent{}
+	for _, test := range []struct {
+		desc    string
+		mkt     Market
+		order   Order
+		wantErr error
+	}{
+		{"zero size", NewDoubleAuction(goods.Grain), Order{Price: 1, Side: Buy, Owner: a}, ErrZeroSize},
+		{"zero price", NewDoubleAuction(goods.Grain), Order{Size: 1, Side: Buy, Owner: a}, ErrBadPrice},
+		{"unknown side", NewDoubleAuction(goods.Grain), Order{Price: 1, Size: 1, Side: 7, Owner: a}, ErrUnknownSide},
+		{"no owner", NewDoubleAuction(goods.Grain), Order{Price: 1, Size: 1, Side: Buy}, ErrNoOwner},
+		{"unknown type", NewDoubleAuction(goods.Grain), Order{Price: 1, Size: 1, Side: Buy, Owner: a, Type: 9}, ErrUnsupported},
+		{"market order doesn't need a price", NewDoubleAuction(goods.Grain), Order{Size: 1, Side: Buy, Owner: a, Type: MarketOrder}, nil},
+		{"type the market doesn't support", NewCallAuction(goods.Grain), Order{Price: 1, Size: 1, Side: Buy, Owner: a, Type: FillOrKill}, ErrUnsupported},
+		{"posted prices need a price", NewPostedPrice(goods.Grain, 1, rng.NewSource(1)), Order{Size: 1, Side: Sell, Owner: a, Type: MarketOrder}, ErrUnsupported},
+	} {
+		id, err := test.mkt.Post(&test.order)
+		if err != test.wantErr {
+			t.Errorf("%s: got %v, want %v", test.desc, err, test.wantErr)
+		}
+		if err != nil && id != 0 {
+			t.Errorf("%s: rejected order got ID %d", test.desc, id)
+		}
+
+		test.mkt.Reset()
+		want := 0
+		if test.wantErr != nil {
+			want = 1
+		}
+		if test.mkt.Rejections().Total() != want {
+			t.Errorf("%s: got rejections %+v, want %d in total", test.desc, test.mkt.Rejections(), want)
+		}
+	}
+}
+
+// repostingAgent tries to post its unfilled orders again straight away.
+type repostingAgent struct {
+	fakeAgent
+	mkt Market
+	err error
+}
+
+func (ra *repostingAgent) OnUnfilled(g goods.Good, s Side, id OrderID, q Size) {
+	_, ra.err = ra.mkt.Post(&Order{Price: 1, Size: q, Side: s, Owner: ra})
+}
+
+func TestClosedDuringReset(t *testing.T) {
+	for _, mkt := range []Market{NewDoubleAuction(goods.Grain), NewCallAuction(goods.Grain)} {
+		a := &repostingAgent{mkt: mkt}
+		mkt.Post(&Order{Price: 1, Size: 1, Side: Buy, Owner: a})
+		mkt.Reset()
+		if a.err != ErrClosed {
+			t.Errorf("%T: posting during reset got %v, want %v", mkt, a.err, ErrClosed)
+		}
+		if _, err := mkt.Post(&Order{Price: 1, Size: 1, Side: Buy, Owner: a}); err != nil {
+			t.Errorf("%T: posting after reset got %v, want the market open again", mkt, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package market
+
+import (
+	"fmt"
+
+	"github.com/robbrit/econerra/goods"
+)
+
+// Strict makes markets check their order books after every order is posted
+// (and amended or cancelled, in the double auction), and check every fill as
+// it happens. Markets panic if anything is wrong, since that means there's a
+// bug in the market. The checks are slow, so they are off by default; turn
+// them on for tests and debugging, before any markets are used.
+var Strict = false
+
+// verify panics if strict mode is on and a market's book is broken.
+func verify(good goods.Good, invariants func() error) {
+	if !Strict {
+		return
+	}
+	if err := invariants(); err != nil {
+		panic(fmt.Sprintf("market: %s market is broken: %s", good, err))
+	}
+}
+
+// verifyFill panics if strict mode is on and a fill is for nothing.
+func verifyFill(good goods.Good, buy, sell *Order, size Size) {
+	if Strict && size == 0 {
+		panic(fmt.Sprintf("market: %s market filled orders %d and %d with no size", good, buy.ID, sell.ID))
+	}
+}
+
+// invariants checks that the book is a valid pair of heaps, that every order
+// in it can be found by ID, and that the book isn't crossed.
+func (m *doubleAuction) invariants() error {
+	if err := checkHeap(&m.bids, Buy); err != nil {
+		return fmt.Errorf("bids: %s", err)
+	}
+	if err := checkHeap(&m.offers, Sell); err != nil {
+		return fmt.Errorf("offers: %s", err)
+	}
+
+	if n := m.bids.Len() + m.offers.Len(); n != len(m.orders) {
+		return fmt.Errorf("%d orders in the book but %d by ID", n, len(m.orders))
+	}
+	for _, h := range []orderHeap{&m.bids, &m.offers} {
+		for i := 0; i < h.Len(); i++ {
+			if o := h.At(i); m.orders[o.ID] != o {
+				return fmt.Errorf("order %d can't be found by ID", o.ID)
+			}
+		}
+	}
+
+	if m.bids.Len() > 0 && m.offers.Len() > 0 && m.bids.Peek().Price >= m.offers.Peek().Price {
+		return fmt.Errorf("book is crossed, bid %s and ask %s", m.bids.Peek().Price, m.offers.Peek().Price)
+	}
+	return nil
+}
+
+// checkHeap checks that every order in one side of a book is a resting limit
+// order for something, knows where it is, and isn't better than its parent.
+func checkHeap(h orderHeap, side Side) error {
+	for i := 0; i < h.Len(); i++ {
+		o := h.At(i)
+		switch {
+		case o.Side != side:
+			return fmt.Errorf("order %d is on the wrong side", o.ID)
+		case o.Type != LimitOrder:
+			return fmt.Errorf("order %d isn't a limit order", o.ID)
+		case o.Size == 0:
+			return fmt.Errorf("order %d has no size", o.ID)
+		case o.index != i:
+			return fmt.Errorf("order %d is at %d but thinks it's at %d", o.ID, i, o.index)
+		case i > 0 && h.Less(i, (i-1)/2):
+			return fmt.Errorf("order %d is ahead of its parent", o.ID)
+		}
+	}
+	return nil
+}
+
+// invariants checks that every order in a batch is on the right side and is
+// for something.
+func (b *batch) invariants() error {
+	for _, q := range []struct {
+		orders []*Order
+		side   Side
+	}{{b.bids, Buy}, {b.offers, Sell}} {
+		for _, o := range q.orders {
+			if o.Side != q.side {
+				return fmt.Errorf("order %d is on the wrong side", o.ID)
+			}
+			if o.Size == 0 {
+				return fmt.Errorf("order %d has no size", o.ID)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package market
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+)
+
+// Check the books in every market test.
+func TestMain(m *testing.M) {
+	Strict = true
+	os.Exit(m.Run())
+}
+
+// broken posts an order after breaking a market, giving what it panicked with.
+func broken(m Market, breakIt func()) (msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg = r.(string)
+		}
+	}()
+	breakIt()
+	m.Post(&Order{Price: 1, Size: 1, Side: Buy, Owner: &fakeAgent{}})
+	return ""
+}
+
+func TestStrict(t *testing.T) {
+	newBook := func() *doubleAuction {
+		m := NewDoubleAuction(goods.Grain).(*doubleAuction)
+		a := &fakeAgent{}
+		m.Post(&Order{Price: 5, Size: 1, Side: Buy, Owner: a})
+		m.Post(&Order{Price: 4, Size: 1, Side: Buy, Owner: a})
+		m.Post(&Order{Price: 8, Size: 1, Side: Sell, Owner: a})
+		m.Post(&Order{Price: 9, Size: 1, Side: Sell, Owner: a})
+		return m
+	}
+
+	for _, test := range []struct {
+		desc    string
+		breakIt func(m *doubleAuction)
+		want    string
+	}{
+		{"crossed", func(m *doubleAuction) { m.bids[0].Price = 10 }, "crossed"},
+		{"out of order", func(m *doubleAuction) { m.offers[1].Price = 7 }, "ahead of its parent"},
+		{"empty order", func(m *doubleAuction) { m.offers[0].Size = 0 }, "no size"},
+		{"lost order", func(m *doubleAuction) { delete(m.orders, m.offers[0].ID) }, "by ID"},
+		{"misplaced order", func(m *doubleAuction) { m.bids[0].index = 1 }, "thinks it's at"},
+	} {
+		m := newBook()
+		if got := broken(m, func() { test.breakIt(m) }); !strings.Contains(got, test.want) {
+			t.Errorf("%s: got panic %q, want one about %q", test.desc, got, test.want)
+		}
+	}
+
+	if got := broken(newBook(), func() {}); got != "" {
+		t.Errorf("got panic %q for a valid book", got)
+	}
+
+	c := NewCallAuction(goods.Grain)
+	c.Post(&Order{Price: 5, Size: 1, Side: Sell, Owner: &fakeAgent{}})
+	if got := broken(c, func() { c.(*callAuction).offers[0].Size = 0 }); !strings.Contains(got, "no size") {
+		t.Errorf("got panic %q for an empty order in a call auction", got)
+	}
+}
+
+func TestStrictFill(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("got no panic for an empty fill")
+		}
+	}()
+	a := &fakeAgent{}
+	var d marketData
+	d.execute(goods.Grain, &Order{Owner: a}, &Order{Owner: a}, 1, 0)
+}
@@ -0,0 +1,115 @@
+package market
+
+import (
+	"sort"
+
+	"github.com/robbrit/econerra/goods"
+)
+
+// A Trade is a single fill between two orders.
+type Trade struct {
+	Price Price   `json:"price"`
+	Size  Size    `json:"size"`
+	Buy   OrderID `json:"buy"`
+	Sell  OrderID `json:"sell"`
+}
+
+// A Tape is the trades made during a trading period, in the order they were
+// made.
+type Tape []Trade
+
+// Open gets the price of the first trade, or 0 if there were none.
+func (t Tape) Open() Price {
+	if len(t) == 0 {
+		return 0
+	}
+	return t[0].Price
+}
+
+// Close gets the price of the last trade, or 0 if there were none.
+func (t Tape) Close() Price {
+	if len(t) == 0 {
+		return 0
+	}
+	return t[len(t)-1].Price
+}
+
+// VWAP gets the volume-weighted average price, or 0 if there were no trades.
+func (t Tape) VWAP() float64 {
+	value, volume := 0.0, 0.0
+	for _, trade := range t {
+		value += trade.Price.Float() * trade.Size.Float()
+		volume += trade.Size.Float()
+	}
+	if volume == 0 {
+		return 0
+	}
+	return value / volume
+}
+
+// A Level is all the open orders on one side of a market at a single price.
+type Level struct {
+	Price Price `json:"price"`
+	// The total size of all the orders.
+	Size   Size `json:"size"`
+	Orders int  `json:"orders"`
+}
+
+// Depth is the open orders in a market, added up by price, best price first.
+type Depth struct {
+	Bids []Level `json:"bids"`
+	Asks []Level `json:"asks"`
+}
+
+// levels adds up orders by price. Orders without a price are left out.
+func levels(orders []*Order, better func(a, b Price) bool) []Level {
+	var ls []Level
+	for _, o := range orders {
+		if o.Size == 0 || o.Type == MarketOrder {
+			continue
+		}
+		i := sort.Search(len(ls), func(i int) bool { return !better(ls[i].Price, o.Price) })
+		if i == len(ls) || ls[i].Price != o.Price {
+			ls = append(ls, Level{})
+			copy(ls[i+1:], ls[i:])
+			ls[i] = Level{Price: o.Price}
+		}
+		ls[i].Size += o.Size
+		ls[i].Orders++
+	}
+	return ls
+}
+
+// marketData keeps the trade tape, the depth and the rejected orders of a
+// market for the last trading period.
+type marketData struct {
+	tape           Tape
+	lastTape       Tape
+	depth          Depth
+	rejections     Rejections
+	lastRejections Rejections
+	// Markets don't take orders while they are being reset.
+	closed bool
+}
+
+// execute fills two orders with each other and records the trade.
+func (d *marketData) execute(good goods.Good, buy, sell *Order, price Price, size Size) {
+	verifyFill(good, buy, sell, size)
+	fill(good, buy, sell, price, size)
+	d.tape = append(d.tape, Trade{price, size, buy.ID, sell.ID})
+}
+
+// endPeriod starts a new trading period, given the orders that are still open.
+func (d *marketData) endPeriod(bids, asks []*Order) {
+	d.lastTape = d.tape
+	d.tape = nil
+	d.lastRejections = d.rejections
+	d.rejections = Rejections{}
+	d.depth = Depth{
+		Bids: levels(bids, func(a, b Price) bool { return a > b }),
+		Asks: levels(asks, func(a, b Price) bool { return a < b }),
+	}
+}
+
+func (d *marketData) Trades() Tape { return d.lastTape }
+func (d *marketData) Depth() Depth { return d.depth }
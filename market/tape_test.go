@@ -0,0 +1,56 @@
+package market
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+)
+
+func TestTape(t *testing.T) {
+	tape := Tape{{Price: 10, Size: 1}, {Price: 13, Size: 2}, {Price: 12, Size: 1}}
+	if tape.Open() != 10 || tape.Close() != 12 {
+		t.Errorf("got open %d and close %d, want 10 and 12", tape.Open(), tape.Close())
+	}
+	if got := tape.VWAP(); got != 12 {
+		t.Errorf("got VWAP %g, want 12", got)
+	}
+
+	var empty Tape
+	if empty.Open() != 0 || empty.Close() != 0 || empty.VWAP() != 0 {
+		t.Errorf("got %d, %d and %g for an empty tape, want zeros", empty.Open(), empty.Close(), empty.VWAP())
+	}
+}
+
+func TestTradesAndDepth(t *testing.T) {
+	m := NewDoubleAuction(goods.Grain)
+	a := &fakeAgent{}
+
+	sell, _ := m.Post(&Order{Price: 10, Size: 5, Side: Sell, Owner: a})
+	m.Post(&Order{Price: 12, Size: 5, Side: Sell, Owner: a})
+	m.Post(&Order{Price: 12, Size: 2, Side: Sell, Owner: a})
+	m.Post(&Order{Price: 8, Size: 3, Side: Buy, Owner: a})
+	buy, _ := m.Post(&Order{Price: 11, Size: 7, Side: Buy, Owner: a})
+	m.Post(&Order{Price: 9, Size: 1, Side: Buy, Owner: a})
+
+	if len(m.Trades()) != 0 {
+		t.Errorf("got trades %v before the end of the period, want none", m.Trades())
+	}
+	m.Reset()
+
+	if want := (Tape{{Price: 10, Size: 5, Buy: buy, Sell: sell}}); !reflect.DeepEqual(m.Trades(), want) {
+		t.Errorf("got trades %v, want %v", m.Trades(), want)
+	}
+	want := Depth{
+		Bids: []Level{{Price: 11, Size: 2, Orders: 1}, {Price: 9, Size: 1, Orders: 1}, {Price: 8, Size: 3, Orders: 1}},
+		Asks: []Level{{Price: 12, Size: 7, Orders: 2}},
+	}
+	if !reflect.DeepEqual(m.Depth(), want) {
+		t.Errorf("got depth %v, want %v", m.Depth(), want)
+	}
+
+	m.Reset()
+	if len(m.Trades()) != 0 || len(m.Depth().Bids) != 0 {
+		t.Errorf("got trades %v and depth %v after an empty period, want none", m.Trades(), m.Depth())
+	}
+}
@@ -0,0 +1,204 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/sim"
+)
+
+// CSV writes one row per market per cycle, and optionally one row of
+// macroeconomic statistics per cycle to a second CSV, and one row per price
+// level in each market's order book per cycle to a third.
+type CSV struct {
+	w       *csv.Writer
+	macro   *csv.Writer
+	depth   *csv.Writer
+	closers []io.Closer
+}
+
+// NewCSV creates a CSV sink, writing the headers right away. The macro and
+// depth writers can be nil to skip macroeconomic statistics and order book
+// depth. Writers that are io.Closers will be closed along with the sink.
+func NewCSV(w, macro, depth io.Writer) (*CSV, error) {
+	c := &CSV{w: csv.NewWriter(w)}
+	for _, w := range []io.Writer{w, macro, depth} {
+		if closer, ok := w.(io.Closer); ok {
+			c.closers = append(c.closers, closer)
+		}
+	}
+
+	if macro != nil {
+		c.macro = csv.NewWriter(macro)
+		err := c.macro.Write([]string{
+			"Iteration",
+			"NominalGDP",
+			"RealGDP",
+			"CPI",
+			"Inflation",
+			"UnemploymentRate",
+			"AverageWage",
+			"RealWage",
+			"WageShare",
+			"PriceDispersion",
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if depth != nil {
+		c.depth = csv.NewWriter(depth)
+		err := c.depth.Write([]string{
+			"Iteration",
+			"Good",
+			"Side",
+			"Price",
+			"Size",
+			"Orders",
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := c.w.Write([]string{
+		"Iteration",
+		"Good",
+		"Bid",
+		"Ask",
+		"Low",
+		"High",
+		"Volume",
+		"Supply",
+		"Demand",
+		"Entries",
+		"Exits",
+		"Vacancies",
+		"Hires",
+		"Quits",
+		"Layoffs",
+		"Expiries",
+		"Open",
+		"Close",
+		"VWAP",
+		"Trades",
+		"RejectedZeroSize",
+		"RejectedBadPrice",
+		"RejectedUnknownSide",
+		"RejectedNoOwner",
+		"RejectedClosed",
+		"RejectedUnsupported",
+	})
+	return c, err
+}
+
+// OnMarket does nothing, rows are written at the end of the cycle.
+func (c *CSV) OnMarket(int, market.Market) error { return nil }
+
+// OnCycleEnd writes the rows for every market.
+func (c *CSV) OnCycleEnd(res *sim.CycleResult) error {
+	for _, r := range res.Markets {
+		c.w.Write([]string{
+			fmt.Sprintf("%d", res.Cycle),
+			fmt.Sprintf("%s", r.Good),
+			fmt.Sprintf("%s", r.Bid),
+			fmt.Sprintf("%s", r.Ask),
+			fmt.Sprintf("%s", r.Low),
+			fmt.Sprintf("%s", r.High),
+			fmt.Sprintf("%s", r.Volume),
+			fmt.Sprintf("%s", r.Supply),
+			fmt.Sprintf("%s", r.Demand),
+			fmt.Sprintf("%d", r.Entries),
+			fmt.Sprintf("%d", r.Exits),
+			fmt.Sprintf("%d", r.Vacancies),
+			fmt.Sprintf("%d", r.Hires),
+			fmt.Sprintf("%d", r.Quits),
+			fmt.Sprintf("%d", r.Layoffs),
+			fmt.Sprintf("%d", r.Expiries),
+			fmt.Sprintf("%s", r.Open),
+			fmt.Sprintf("%s", r.Close),
+			fmt.Sprintf("%g", r.VWAP),
+			fmt.Sprintf("%d", r.Trades),
+			fmt.Sprintf("%d", r.Rejections.ZeroSize),
+			fmt.Sprintf("%d", r.Rejections.BadPrice),
+			fmt.Sprintf("%d", r.Rejections.UnknownSide),
+			fmt.Sprintf("%d", r.Rejections.NoOwner),
+			fmt.Sprintf("%d", r.Rejections.Closed),
+			fmt.Sprintf("%d", r.Rejections.Unsupported),
+		})
+	}
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	if err := c.writeDepth(res); err != nil {
+		return err
+	}
+
+	if c.macro == nil {
+		return nil
+	}
+	m := res.Macro
+	c.macro.Write([]string{
+		fmt.Sprintf("%d", res.Cycle),
+		fmt.Sprintf("%g", m.NominalGDP),
+		fmt.Sprintf("%g", m.RealGDP),
+		fmt.Sprintf("%g", m.CPI),
+		fmt.Sprintf("%g", m.Inflation),
+		fmt.Sprintf("%g", m.UnemploymentRate),
+		fmt.Sprintf("%g", m.AverageWage),
+		fmt.Sprintf("%g", m.RealWage),
+		fmt.Sprintf("%g", m.WageShare),
+		fmt.Sprintf("%g", m.PriceDispersion),
+	})
+	return c.macro.Error()
+}
+
+func (c *CSV) writeDepth(res *sim.CycleResult) error {
+	if c.depth == nil {
+		return nil
+	}
+	for _, r := range res.Markets {
+		if r.Depth == nil {
+			continue
+		}
+		for _, side := range []struct {
+			name   string
+			levels []market.Level
+		}{{"Bid", r.Depth.Bids}, {"Ask", r.Depth.Asks}} {
+			for _, l := range side.levels {
+				c.depth.Write([]string{
+					fmt.Sprintf("%d", res.Cycle),
+					fmt.Sprintf("%s", r.Good),
+					side.name,
+					fmt.Sprintf("%s", l.Price),
+					fmt.Sprintf("%s", l.Size),
+					fmt.Sprintf("%d", l.Orders),
+				})
+			}
+		}
+	}
+	return c.depth.Error()
+}
+
+// Close flushes any buffered rows.
+func (c *CSV) Close() error {
+	var err error
+	for _, w := range []*csv.Writer{c.w, c.macro, c.depth} {
+		if w == nil {
+			continue
+		}
+		w.Flush()
+		if werr := w.Error(); err == nil {
+			err = werr
+		}
+	}
+	for _, closer := range c.closers {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
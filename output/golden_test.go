@@ -0,0 +1,121 @@
+package output
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/robbrit/econerra/experiment"
+	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/scenario"
+	"github.com/robbrit/econerra/sim"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata with the current output.")
+
+// TestGolden runs every scenario in the scenarios directory, and checks that
+// the CSV output is byte for byte what it was when the golden files were
+// written. Changes that are meant to alter results should rewrite them with
+// go test ./output -update.
+func TestGolden(t *testing.T) {
+	paths, err := filepath.Glob("../scenarios/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Some scenarios change the precision.
+	defer market.SetPrecision(0)
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			scen, err := scenario.Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rows, macro, depth bytes.Buffer
+			sink, err := NewCSV(&rows, &macro, &depth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := sim.New(scen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.AddObserver(sink)
+			if _, err := s.Run(scen.Cycles); err != nil {
+				t.Fatal(err)
+			}
+			if err := sink.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", name+".csv")
+			files := []struct {
+				path string
+				got  []byte
+			}{
+				{golden, rows.Bytes()},
+				{MacroPath(golden), macro.Bytes()},
+				{DepthPath(golden), depth.Bytes()},
+			}
+			if *update {
+				for _, f := range files {
+					if err := ioutil.WriteFile(f.path, f.got, 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				return
+			}
+
+			same := true
+			for _, f := range files {
+				want, err := ioutil.ReadFile(f.path)
+				if err != nil {
+					t.Fatalf("%s, run with -update to write it", err)
+				}
+				if !bytes.Equal(f.got, want) {
+					t.Errorf("output differs from %s", f.path)
+					same = false
+				}
+			}
+			if !same {
+				describeDiff(t, golden, &rows, &macro)
+			}
+		})
+	}
+}
+
+// describeDiff reports which series differ from the golden files, and where
+// they start to.
+func describeDiff(t *testing.T, golden string, rows, macro *bytes.Buffer) {
+	wantRows, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMacro, err := ioutil.ReadFile(MacroPath(golden))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := experiment.ReadCSV(bytes.NewReader(wantRows), bytes.NewReader(wantMacro))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := experiment.ReadCSV(rows, macro)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := experiment.Diff(want, got, experiment.Tolerance{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, d := range diffs {
+		if i == 10 {
+			t.Logf("and %d more series", len(diffs)-i)
+			break
+		}
+		t.Logf("%s differs in %d cycles, first in cycle %d: want %g, got %g", d.Series, d.Cycles, d.Cycle, d.A, d.B)
+	}
+}
@@ -0,0 +1,61 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/robbrit/econerra/macro"
+	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/sim"
+)
+
+// JSONLines writes one JSON object per market per cycle and one with the
+// macroeconomic statistics per cycle, each on its own line. The "kind" field
+// says which of the two a line is.
+type JSONLines struct {
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// A marketLine is a line of JSON output for a single market.
+type marketLine struct {
+	Kind  string `json:"kind"`
+	Cycle int    `json:"cycle"`
+	sim.MarketResult
+}
+
+// A macroLine is a line of JSON output for the whole economy.
+type macroLine struct {
+	Kind  string `json:"kind"`
+	Cycle int    `json:"cycle"`
+	macro.Stats
+}
+
+// NewJSONLines creates a JSON Lines sink. If w is an io.Closer it will be
+// closed along with the sink.
+func NewJSONLines(w io.Writer) *JSONLines {
+	j := &JSONLines{enc: json.NewEncoder(w)}
+	j.closer, _ = w.(io.Closer)
+	return j
+}
+
+// OnMarket does nothing, lines are written at the end of the cycle.
+func (j *JSONLines) OnMarket(int, market.Market) error { return nil }
+
+// OnCycleEnd writes a line for every market, then one for the economy.
+func (j *JSONLines) OnCycleEnd(res *sim.CycleResult) error {
+	for _, r := range res.Markets {
+		if err := j.enc.Encode(marketLine{"market", res.Cycle, r}); err != nil {
+			return err
+		}
+	}
+	return j.enc.Encode(macroLine{"macro", res.Cycle, res.Macro})
+}
+
+// Close closes the underlying writer, if possible.
+func (j *JSONLines) Close() error {
+	if j.closer != nil {
+		return j.closer.Close()
+	}
+	return nil
+}
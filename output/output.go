@@ -0,0 +1,88 @@
+// Package output contains sinks that record simulation results.
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/robbrit/econerra/sim"
+)
+
+// A Sink is an observer that writes simulation results somewhere. It must be
+// closed once the simulation is over.
+type Sink interface {
+	sim.Observer
+	io.Closer
+}
+
+// Formats lists the output formats that can be passed to Open.
+var Formats = []string{"csv", "jsonl", "sqlite"}
+
+// Open creates a sink writing the given format to a file at path.
+func Open(format, path string) (Sink, error) {
+	switch format {
+	case "csv":
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		m, err := os.Create(MacroPath(path))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		d, err := os.Create(DepthPath(path))
+		if err != nil {
+			f.Close()
+			m.Close()
+			return nil, err
+		}
+		return NewCSV(f, m, d)
+	case "jsonl":
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewJSONLines(f), nil
+	case "sqlite":
+		return NewSQLite(path)
+	}
+	return nil, fmt.Errorf("unknown output format %q, want one of %s", format, strings.Join(Formats, ", "))
+}
+
+// MacroPath gives the path that the CSV sink writes macroeconomic statistics
+// to, next to the market rows: "output.csv" becomes "output_macro.csv".
+func MacroPath(path string) string {
+	return siblingPath(path, "_macro")
+}
+
+// DepthPath gives the path that the CSV sink writes order book depth to:
+// "output.csv" becomes "output_depth.csv".
+func DepthPath(path string) string {
+	return siblingPath(path, "_depth")
+}
+
+func siblingPath(path, suffix string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + suffix + ext
+}
+
+// A Spec says where to write output to, in the form "format:path".
+type Spec struct {
+	Format string
+	Path   string
+}
+
+// ParseSpec reads a spec like "jsonl:output.jsonl".
+func ParseSpec(s string) (Spec, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Spec{}, fmt.Errorf("bad output %q, want format:path", s)
+	}
+	return Spec{parts[0], parts[1]}, nil
+}
+
+func (s Spec) String() string { return s.Format + ":" + s.Path }
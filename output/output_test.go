@@ -0,0 +1,213 @@
+package output
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/robbrit/econerra/agents"
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/scenario"
+	"github.com/robbrit/econerra/sim"
+)
+
+var testResult = &sim.CycleResult{
+	Cycle: 3,
+	Markets: []sim.MarketResult{
+		{
+			Good: goods.Labour, Bid: 10, Ask: 11, Low: 9, High: 12, Volume: 5, Supply: 7, Demand: 6,
+			EmploymentEvents: agents.EmploymentEvents{Vacancies: 3, Hires: 2, Layoffs: 1},
+			Depth: &market.Depth{
+				Bids: []market.Level{{Price: 10, Size: 4, Orders: 2}, {Price: 8, Size: 1, Orders: 1}},
+				Asks: []market.Level{{Price: 11, Size: 3, Orders: 3}},
+			},
+		},
+		{Good: goods.Meat, Bid: 2, Ask: 3, Volume: 40, Entries: 1, Open: 3, Close: 2, VWAP: 2.5, Trades: 6,
+			Rejections: market.Rejections{BadPrice: 2, Closed: 1}},
+	},
+}
+
+func TestCSV(t *testing.T) {
+	var buf, depth bytes.Buffer
+	c, err := NewCSV(&buf, nil, &depth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.OnCycleEnd(testResult); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `Iteration,Good,Bid,Ask,Low,High,Volume,Supply,Demand,Entries,Exits,Vacancies,Hires,Quits,Layoffs,Expiries,Open,Close,VWAP,Trades,RejectedZeroSize,RejectedBadPrice,RejectedUnknownSide,RejectedNoOwner,RejectedClosed,RejectedUnsupported
+3,Labour,10,11,9,12,5,7,6,0,0,3,2,0,1,0,0,0,0,0,0,0,0,0,0,0
+3,Meat,2,3,0,0,40,0,0,1,0,0,0,0,0,0,3,2,2.5,6,0,2,0,0,1,0
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	want = `Iteration,Good,Side,Price,Size,Orders
+3,Labour,Bid,10,4,2
+3,Labour,Bid,8,1,1
+3,Labour,Ask,11,3,3
+`
+	if got := depth.String(); got != want {
+		t.Errorf("got depth:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	j := NewJSONLines(&buf)
+	if err := j.OnCycleEnd(testResult); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+	var got marketLine
+	if err := json.Unmarshal([]byte(lines[1]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (marketLine{"market", 3, testResult.Markets[1]}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var labour marketLine
+	if err := json.Unmarshal([]byte(lines[0]), &labour); err != nil {
+		t.Fatal(err)
+	}
+	if labour.Depth == nil || !reflect.DeepEqual(*labour.Depth, *testResult.Markets[0].Depth) {
+		t.Errorf("got depth %v, want %v", labour.Depth, testResult.Markets[0].Depth)
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.db")
+
+	// A table left by an older version, without most of the columns.
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE markets (cycle INTEGER, good TEXT, PRIMARY KEY (cycle, good))"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writing the same run twice replaces the first one.
+	for i := 0; i < 2; i++ {
+		s, err := NewSQLite(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.OnCycleEnd(testResult); err != nil {
+			t.Fatalf("run %d: %s", i, err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var rows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM markets").Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if rows != len(testResult.Markets) {
+		t.Errorf("got %d market rows, want %d", rows, len(testResult.Markets))
+	}
+
+	var volume, entries, badPrice int
+	err = db.QueryRow("SELECT volume, entries, rejected_bad_price FROM markets WHERE cycle = 3 AND good = 'Meat'").Scan(&volume, &entries, &badPrice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volume != 40 || entries != 1 || badPrice != 2 {
+		t.Errorf("got volume %d, entries %d and bad prices %d, want 40, 1 and 2", volume, entries, badPrice)
+	}
+
+	var levels, size int
+	err = db.QueryRow("SELECT COUNT(*), SUM(size) FROM depth WHERE cycle = 3 AND good = 'Labour' AND side = 'Bid'").Scan(&levels, &size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if levels != 2 || size != 5 {
+		t.Errorf("got %d bid levels with size %d, want 2 and 5", levels, size)
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	spec, err := ParseSpec("jsonl:out/results.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Spec{"jsonl", "out/results.jsonl"}); spec != want {
+		t.Errorf("got %v, want %v", spec, want)
+	}
+	if _, err := ParseSpec("output.csv"); err == nil {
+		t.Error("got no error for a spec without a format")
+	}
+}
+
+func TestPanel(t *testing.T) {
+	scen := scenario.Default()
+	scen.Workers = 50
+	s, err := sim.New(scen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var workers, firms bytes.Buffer
+	p, err := NewPanel(s, &workers, &firms, PanelOptions{
+		SampleRate: 1,
+		Every:      5,
+		Goods:      []goods.Good{goods.Meat},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddObserver(p)
+	if _, err := s.Run(10); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Header plus two cycles.
+	if got, want := strings.Count(workers.String(), "\n"), 1+2*50; got != want {
+		t.Errorf("got %d worker lines, want %d", got, want)
+	}
+	meatFirms := scen.Good(goods.Meat).Firms
+	if got, want := strings.Count(firms.String(), "\n"), 1+2*meatFirms; got != want {
+		t.Errorf("got %d firm lines, want %d", got, want)
+	}
+	if strings.Contains(firms.String(), "Grain") {
+		t.Error("firms panel includes grain firms")
+	}
+}
+
+func TestPanelSampling(t *testing.T) {
+	p := &Panel{opts: PanelOptions{SampleRate: 0.1, Seed: 1}}
+	sampled := 0
+	for id := 0; id < 10000; id++ {
+		if p.sampled(id) {
+			sampled++
+		}
+		if p.sampled(id) != p.sampled(id) {
+			t.Fatalf("agent %d is not consistently sampled", id)
+		}
+	}
+	if sampled < 900 || sampled > 1100 {
+		t.Errorf("sampled %d of 10000 agents, want about 1000", sampled)
+	}
+}
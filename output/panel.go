@@ -0,0 +1,197 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/sim"
+)
+
+// PanelOptions controls which agents and cycles end up in a panel, so that
+// large simulations produce a manageable amount of output.
+type PanelOptions struct {
+	// Fraction of agents to include. The same agents are included every cycle.
+	SampleRate float64
+	// Only write every this many cycles.
+	Every int
+	// Only include firms producing these goods. Includes all firms if empty.
+	Goods []goods.Good
+	// Seed for choosing which agents are sampled.
+	Seed int64
+}
+
+// A Panel writes the state of individual agents every cycle, with one CSV for
+// workers and one for firms.
+type Panel struct {
+	sim     *sim.Simulation
+	opts    PanelOptions
+	workers *csv.Writer
+	firms   *csv.Writer
+	goods   map[goods.Good]bool
+	closers []io.Closer
+}
+
+// NewPanel creates a panel of the agents in s. Either of the writers can be
+// nil to skip that kind of agent. Writers that are io.Closers will be closed
+// along with the panel.
+func NewPanel(s *sim.Simulation, workers, firms io.Writer, opts PanelOptions) (*Panel, error) {
+	if opts.Every <= 0 {
+		opts.Every = 1
+	}
+	p := &Panel{sim: s, opts: opts}
+
+	if len(opts.Goods) > 0 {
+		p.goods = map[goods.Good]bool{}
+		for _, good := range opts.Goods {
+			p.goods[good] = true
+		}
+	}
+
+	if workers != nil {
+		p.workers = csv.NewWriter(workers)
+		header := []string{"Iteration", "Worker", "Wage", "Employed", "Money"}
+		for _, good := range goods.AllGoods {
+			header = append(header, "Demand"+good.String())
+		}
+		for _, good := range goods.AllGoods {
+			header = append(header, "Purchases"+good.String())
+		}
+		if err := p.workers.Write(header); err != nil {
+			return nil, err
+		}
+		p.addCloser(workers)
+	}
+
+	if firms != nil {
+		p.firms = csv.NewWriter(firms)
+		err := p.firms.Write([]string{
+			"Iteration",
+			"Firm",
+			"Good",
+			"Wage",
+			"Price",
+			"TargetWorkers",
+			"WorkersHired",
+			"TargetSales",
+			"SalesMade",
+			"Inventory",
+			"Money",
+			"Profit",
+		})
+		if err != nil {
+			return nil, err
+		}
+		p.addCloser(firms)
+	}
+
+	return p, nil
+}
+
+func (p *Panel) addCloser(w io.Writer) {
+	if c, ok := w.(io.Closer); ok {
+		p.closers = append(p.closers, c)
+	}
+}
+
+// sampled decides whether an agent is part of the panel. It hashes the agent's
+// ID rather than drawing a random number, so that the choice is the same
+// every cycle and doesn't disturb the simulation's random numbers.
+func (p *Panel) sampled(id int) bool {
+	if p.opts.SampleRate >= 1 {
+		return true
+	}
+	// splitmix64 finalizer.
+	x := uint64(id) + uint64(p.opts.Seed)*0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x ^= x >> 31
+	return float64(x>>11)/float64(1<<53) < p.opts.SampleRate
+}
+
+// OnMarket does nothing, rows are written at the end of the cycle.
+func (p *Panel) OnMarket(int, market.Market) error { return nil }
+
+// OnCycleEnd writes a row for every sampled agent.
+func (p *Panel) OnCycleEnd(res *sim.CycleResult) error {
+	if res.Cycle%p.opts.Every != 0 {
+		return nil
+	}
+	cycle := fmt.Sprintf("%d", res.Cycle)
+
+	if p.workers != nil {
+		for _, w := range p.sim.Workers() {
+			id := p.sim.ID(w)
+			if !p.sampled(id) {
+				continue
+			}
+			row := []string{
+				cycle,
+				fmt.Sprintf("%d", id),
+				fmt.Sprintf("%s", w.Wage()),
+				fmt.Sprintf("%t", !w.Unemployed()),
+				fmt.Sprintf("%s", w.Money()),
+			}
+			for _, good := range goods.AllGoods {
+				row = append(row, fmt.Sprintf("%s", w.TargetDemand(good)))
+			}
+			for _, good := range goods.AllGoods {
+				row = append(row, fmt.Sprintf("%s", w.Purchases(good)))
+			}
+			p.workers.Write(row)
+		}
+		if err := p.workers.Error(); err != nil {
+			return err
+		}
+	}
+
+	if p.firms != nil {
+		for _, f := range p.sim.Firms() {
+			id := p.sim.ID(f)
+			if (p.goods != nil && !p.goods[f.Good()]) || !p.sampled(id) {
+				continue
+			}
+			p.firms.Write([]string{
+				cycle,
+				fmt.Sprintf("%d", id),
+				fmt.Sprintf("%s", f.Good()),
+				fmt.Sprintf("%s", f.Wage()),
+				fmt.Sprintf("%s", f.Price()),
+				fmt.Sprintf("%s", f.TargetWorkers()),
+				fmt.Sprintf("%s", f.WorkersHired()),
+				fmt.Sprintf("%s", f.TargetSupply(f.Good())),
+				fmt.Sprintf("%s", f.SalesMade()),
+				fmt.Sprintf("%s", f.Inventory()),
+				fmt.Sprintf("%s", f.Money()),
+				fmt.Sprintf("%g", f.Profit()),
+			})
+		}
+		if err := p.firms.Error(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close flushes any buffered rows.
+func (p *Panel) Close() error {
+	var err error
+	for _, w := range []*csv.Writer{p.workers, p.firms} {
+		if w == nil {
+			continue
+		}
+		w.Flush()
+		if werr := w.Error(); err == nil {
+			err = werr
+		}
+	}
+	for _, c := range p.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,179 @@
+package output
+
+import (
+	"database/sql"
+
+	// Registers the sqlite3 driver.
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/sim"
+)
+
+const createMarkets = `CREATE TABLE markets (
+	cycle INTEGER NOT NULL,
+	good TEXT NOT NULL,
+	bid NUMERIC NOT NULL,
+	ask NUMERIC NOT NULL,
+	low NUMERIC NOT NULL,
+	high NUMERIC NOT NULL,
+	volume NUMERIC NOT NULL,
+	supply NUMERIC NOT NULL,
+	demand NUMERIC NOT NULL,
+	entries INTEGER NOT NULL,
+	exits INTEGER NOT NULL,
+	vacancies INTEGER NOT NULL,
+	hires INTEGER NOT NULL,
+	quits INTEGER NOT NULL,
+	layoffs INTEGER NOT NULL,
+	expiries INTEGER NOT NULL,
+	open NUMERIC NOT NULL,
+	close NUMERIC NOT NULL,
+	vwap REAL NOT NULL,
+	trades INTEGER NOT NULL,
+	rejected_zero_size INTEGER NOT NULL,
+	rejected_bad_price INTEGER NOT NULL,
+	rejected_unknown_side INTEGER NOT NULL,
+	rejected_no_owner INTEGER NOT NULL,
+	rejected_closed INTEGER NOT NULL,
+	rejected_unsupported INTEGER NOT NULL,
+	PRIMARY KEY (cycle, good)
+)`
+
+const createDepth = `CREATE TABLE depth (
+	cycle INTEGER NOT NULL,
+	good TEXT NOT NULL,
+	side TEXT NOT NULL,
+	price NUMERIC NOT NULL,
+	size NUMERIC NOT NULL,
+	orders INTEGER NOT NULL,
+	PRIMARY KEY (cycle, good, side, price)
+)`
+
+const createMacro = `CREATE TABLE macro (
+	cycle INTEGER NOT NULL PRIMARY KEY,
+	nominal_gdp REAL NOT NULL,
+	real_gdp REAL NOT NULL,
+	cpi REAL NOT NULL,
+	inflation REAL NOT NULL,
+	unemployment_rate REAL NOT NULL,
+	average_wage REAL NOT NULL,
+	real_wage REAL NOT NULL,
+	wage_share REAL NOT NULL,
+	price_dispersion REAL NOT NULL
+)`
+
+const insertMarket = `INSERT INTO markets
+	(cycle, good, bid, ask, low, high, volume, supply, demand, entries, exits,
+	 vacancies, hires, quits, layoffs, expiries, open, close, vwap, trades,
+	 rejected_zero_size, rejected_bad_price, rejected_unknown_side, rejected_no_owner, rejected_closed,
+	 rejected_unsupported)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+const insertDepth = `INSERT INTO depth
+	(cycle, good, side, price, size, orders)
+	VALUES (?, ?, ?, ?, ?, ?)`
+
+const insertMacro = `INSERT INTO macro
+	(cycle, nominal_gdp, real_gdp, cpi, inflation, unemployment_rate, average_wage, real_wage,
+	 wage_share, price_dispersion)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// SQLite writes results into an SQLite database, with a "markets" table for the
+// markets, a "depth" table for their order books and a "macro" table for the
+// economy as a whole.
+type SQLite struct {
+	db *sql.DB
+}
+
+// NewSQLite opens (or creates) the database at path and sets up its tables.
+// Like the other outputs overwriting their files, the tables from any earlier
+// run are dropped and made again, so a database from an older version with
+// different columns can be reused.
+func NewSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := createTables(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLite{db}, nil
+}
+
+// createTables replaces the tables in a single transaction, so that a
+// failure leaves the database as it was.
+func createTables(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS markets", createMarkets,
+		"DROP TABLE IF EXISTS depth", createDepth,
+		"DROP TABLE IF EXISTS macro", createMacro,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// OnMarket does nothing, rows are written at the end of the cycle.
+func (s *SQLite) OnMarket(int, market.Market) error { return nil }
+
+// OnCycleEnd writes the rows for the cycle in a single transaction.
+func (s *SQLite) OnCycleEnd(res *sim.CycleResult) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, r := range res.Markets {
+		rj := r.Rejections
+		_, err := tx.Exec(insertMarket,
+			res.Cycle, r.Good.String(), r.Bid.Float(), r.Ask.Float(), r.Low.Float(), r.High.Float(),
+			r.Volume.Float(), r.Supply.Float(), r.Demand.Float(), r.Entries, r.Exits,
+			r.Vacancies, r.Hires, r.Quits, r.Layoffs, r.Expiries, r.Open.Float(), r.Close.Float(), r.VWAP, r.Trades,
+			rj.ZeroSize, rj.BadPrice, rj.UnknownSide, rj.NoOwner, rj.Closed, rj.Unsupported)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := insertLevels(tx, res.Cycle, r); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	m := res.Macro
+	_, err = tx.Exec(insertMacro,
+		res.Cycle, m.NominalGDP, m.RealGDP, m.CPI, m.Inflation, m.UnemploymentRate, m.AverageWage, m.RealWage,
+		m.WageShare, m.PriceDispersion)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertLevels(tx *sql.Tx, cycle int, r sim.MarketResult) error {
+	if r.Depth == nil {
+		return nil
+	}
+	for _, l := range r.Depth.Bids {
+		if _, err := tx.Exec(insertDepth, cycle, r.Good.String(), "Bid", l.Price.Float(), l.Size.Float(), l.Orders); err != nil {
+			return err
+		}
+	}
+	for _, l := range r.Depth.Asks {
+		if _, err := tx.Exec(insertDepth, cycle, r.Good.String(), "Ask", l.Price.Float(), l.Size.Float(), l.Orders); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *SQLite) Close() error { return s.db.Close() }
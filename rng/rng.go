@@ -0,0 +1,60 @@
+// Package rng has a source of random numbers whose state can be saved and
+// restored, so that a simulation can be stopped and picked up again later
+// with the same random numbers.
+package rng
+
+import "math/rand"
+
+// A Source is a math/rand source that counts how many numbers it has given
+// out. It gives the same numbers as rand.NewSource with the same seed.
+type Source struct {
+	src   rand.Source64
+	seed  int64
+	draws uint64
+}
+
+// NewSource creates a source with the given seed.
+func NewSource(seed int64) *Source {
+	return &Source{src: rand.NewSource(seed).(rand.Source64), seed: seed}
+}
+
+// Int63 gives a random non-negative int64.
+func (s *Source) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+// Uint64 gives a random uint64.
+func (s *Source) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+// Seed starts the source again from a seed.
+func (s *Source) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.seed = seed
+	s.draws = 0
+}
+
+// State is where a source is up to: its seed and how many numbers it has
+// given out since it was seeded.
+type State struct {
+	Seed  int64  `json:"seed"`
+	Draws uint64 `json:"draws"`
+}
+
+// State gets where the source is up to.
+func (s *Source) State() State { return State{s.seed, s.draws} }
+
+// Restore puts the source back where it was, by seeding it and skipping the
+// numbers it had already given out.
+func (s *Source) Restore(st State) {
+	s.Seed(st.Seed)
+	// Every number the underlying source gives, whichever way it's asked for,
+	// moves it along by one step.
+	for i := uint64(0); i < st.Draws; i++ {
+		s.src.Uint64()
+	}
+	s.draws = st.Draws
+}
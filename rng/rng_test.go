@@ -0,0 +1,33 @@
+package rng
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	want := rand.New(rand.NewSource(7))
+	s := NewSource(7)
+	r := rand.New(s)
+	for i := 0; i < 100; i++ {
+		if a, b := r.Intn(1000), want.Intn(1000); a != b {
+			t.Fatalf("draw %d: got %d, want %d like rand.NewSource", i, a, b)
+		}
+	}
+	r.Perm(10)
+	r.Float64()
+	r.Uint64()
+
+	saved := s.State()
+	restored := NewSource(0)
+	restored.Restore(saved)
+	if restored.State() != saved {
+		t.Errorf("got state %+v, want %+v", restored.State(), saved)
+	}
+	r2 := rand.New(restored)
+	for i := 0; i < 100; i++ {
+		if a, b := r2.Int63(), r.Int63(); a != b {
+			t.Fatalf("draw %d after restoring: got %d, want %d", i, a, b)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+// Package scenario describes a simulation setup in a file, so that experiments
+// don't require recompiling.
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/robbrit/econerra/agents"
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/rng"
+)
+
+// A Scenario is the full description of a simulation run.
+type Scenario struct {
+	// How many workers are in the economy.
+	Workers int `json:"workers"`
+	// How many cycles the simulation runs for.
+	Cycles int `json:"cycles"`
+	// Seed for the random number generator.
+	Seed int64 `json:"seed"`
+	// How many decimal places prices and quantities have. Loading a scenario
+	// sets this for the whole program, see market.SetPrecision; a scenario made
+	// any other way has to set it by hand, or it can't be run.
+	Precision int `json:"precision,omitempty"`
+	// The wage that all agents start out with.
+	InitialWage market.Price `json:"initial_wage"`
+	// The price that all agents start out with for every good.
+	InitialPrice market.Price `json:"initial_price"`
+	// How much cash each worker starts out with.
+	InitialWorkerMoney market.Price `json:"initial_worker_money"`
+	// How much cash each firm starts out with.
+	InitialFirmMoney market.Price `json:"initial_firm_money"`
+	// What fraction of their savings an unemployed worker spends each iteration.
+	DissavingRate float64 `json:"dissaving_rate"`
+	// How much agents will adjust their price each iteration.
+	Increment market.Price `json:"increment"`
+	// CES elasticity of substitution parameter.
+	Elasticity float64 `json:"elasticity"`
+	// Which mechanism the labour market uses, see MarketKinds.
+	LabourMarket string `json:"labour_market,omitempty"`
+	// How each cycle is split into phases, see Schedules.
+	Schedule string `json:"schedule,omitempty"`
+	// Per-good settings, keyed by the name of the good.
+	Goods map[string]GoodScenario `json:"goods"`
+	// Settings for firms entering and leaving industries. If missing, the
+	// number of firms producing each good stays fixed.
+	EntryExit *EntryExitScenario `json:"entry_exit,omitempty"`
+	// Settings for employment contracts. If missing, workers are hired fresh
+	// every cycle.
+	Employment *EmploymentScenario `json:"employment,omitempty"`
+}
+
+// An EmploymentScenario holds the settings for employment contracts.
+type EmploymentScenario struct {
+	// How many cycles a contract lasts, or 0 for contracts that last until the
+	// worker quits or is laid off.
+	Duration int `json:"duration"`
+	// Matching function parameters for the "matching" labour market.
+	MatchingEfficiency float64 `json:"matching_efficiency,omitempty"`
+	MatchingElasticity float64 `json:"matching_elasticity,omitempty"`
+}
+
+// An EntryExitScenario holds the settings for firm entry and exit.
+type EntryExitScenario struct {
+	// Average profit per firm in an industry above which a new firm enters.
+	EntryProfit float64 `json:"entry_profit"`
+	// How many cycles in a row a firm can make losses before it exits.
+	ExitAfter int `json:"exit_after"`
+	// How much cash a new firm starts out with.
+	EntryMoney market.Price `json:"entry_money"`
+}
+
+// A GoodScenario holds the settings specific to a single good.
+type GoodScenario struct {
+	// How many firms produce this good.
+	Firms int `json:"firms"`
+	// Cobb-Douglas production technology factor.
+	Tech float64 `json:"tech"`
+	// Cobb-Douglas production scale factor.
+	Scale float64 `json:"scale"`
+	// CES utility share factor.
+	Share float64 `json:"share"`
+	// What fraction of unsold stock goes bad each cycle.
+	Spoilage float64 `json:"spoilage"`
+	// Which mechanism the market for this good uses, see MarketKinds.
+	Market string `json:"market,omitempty"`
+	// How many sellers each buyer visits in a posted price market.
+	SampleSize int `json:"sample_size,omitempty"`
+}
+
+// MarketKinds lists the market mechanisms that can be chosen for each good.
+// Leaving the market out of the scenario uses a double auction. The labour
+// market can't be a posted price market, and only the labour market can be a
+// matching market.
+var MarketKinds = []string{"double_auction", "pro_rata_auction", "call_auction", "posted_price", "matching"}
+
+func (s *Scenario) newMarket(kind string, good goods.Good) market.Market {
+	switch kind {
+	case "pro_rata_auction":
+		return market.NewProRataAuction(good)
+	case "call_auction":
+		return market.NewCallAuction(good)
+	case "posted_price":
+		// Each market gets its own random numbers so that it doesn't disturb the rest of the
+		// simulation.
+		r := rng.NewSource(s.Seed + int64(good) + 1)
+		return market.NewPostedPrice(good, s.Good(good).SampleSize, r)
+	case "matching":
+		r := rng.NewSource(s.Seed + int64(good) + 1)
+		return market.NewMatching(good, s.Employment.MatchingEfficiency, s.Employment.MatchingElasticity, r)
+	}
+	return market.NewDoubleAuction(good)
+}
+
+// Schedules lists the ways a cycle can be split into phases. With "single",
+// the default, agents do everything in one pass and then every market clears.
+// With "phased", the labour market clears first, then firms produce, then the
+// goods markets clear, then agents adjust their prices.
+var Schedules = []string{"single", "phased"}
+
+func validSchedule(name string) bool {
+	if name == "" {
+		return true
+	}
+	for _, s := range Schedules {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+func validMarket(kind string) bool {
+	if kind == "" {
+		return true
+	}
+	for _, k := range MarketKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// A FieldError is a validation error for a single field in a scenario.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("scenario: %s: %s", e.Field, e.Reason)
+}
+
+// Default gives the scenario that Econerra has historically been run with.
+func Default() *Scenario {
+	return &Scenario{
+		Workers:      1000,
+		Cycles:       100,
+		Seed:         123456,
+		InitialWage:  market.NewPrice(100),
+		InitialPrice: market.NewPrice(2),
+		// Enough for a firm to hire its first workers.
+		InitialFirmMoney: market.NewPrice(10000),
+		DissavingRate:    0.1,
+		Increment:        market.NewPrice(1),
+		Elasticity:       0.8,
+		Goods: map[string]GoodScenario{
+			goods.Grain.String():      {Firms: 5, Tech: 1000.0, Scale: 0.5, Share: 2.0, Spoilage: 0.01},
+			goods.Vegetables.String(): {Firms: 5, Tech: 800.0, Scale: 0.5, Share: 1.0, Spoilage: 0.2},
+			goods.Meat.String():       {Firms: 15, Tech: 500.0, Scale: 0.5, Share: 5.0, Spoilage: 0.3},
+		},
+	}
+}
+
+// Load reads and validates a scenario from a JSON file.
+func Load(path string) (*Scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads and validates a scenario from JSON, and sets the precision of
+// prices and quantities to the one the scenario asks for.
+func Parse(r io.Reader) (*Scenario, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The precision changes how prices are read, so it has to be set before
+	// reading the rest.
+	var p struct {
+		Precision int `json:"precision"`
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("scenario: %s", err)
+	}
+	if err := market.SetPrecision(p.Precision); err != nil {
+		return nil, &FieldError{"precision", fmt.Sprintf("must be between 0 and %d", market.MaxPrecision)}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	s := &Scenario{}
+	if err := dec.Decode(s); err != nil {
+		return nil, fmt.Errorf("scenario: %s", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate checks that the scenario describes a runnable simulation. The
+// returned error is a *FieldError naming the first bad field.
+func (s *Scenario) Validate() error {
+	switch {
+	case s.Precision < 0 || s.Precision > market.MaxPrecision:
+		return &FieldError{"precision", fmt.Sprintf("must be between 0 and %d", market.MaxPrecision)}
+	case s.Workers <= 0:
+		return &FieldError{"workers", "must be positive"}
+	case s.Cycles <= 0:
+		return &FieldError{"cycles", "must be positive"}
+	case s.InitialWage == 0:
+		return &FieldError{"initial_wage", "must be positive"}
+	case s.InitialPrice == 0:
+		return &FieldError{"initial_price", "must be positive"}
+	case s.DissavingRate < 0 || s.DissavingRate > 1:
+		return &FieldError{"dissaving_rate", "must be between 0 and 1"}
+	case s.Increment == 0:
+		return &FieldError{"increment", "must be positive"}
+	case s.Elasticity <= 0:
+		return &FieldError{"elasticity", "must be positive"}
+	case !validMarket(s.LabourMarket):
+		return &FieldError{"labour_market", "unknown market " + s.LabourMarket}
+	case s.LabourMarket == "posted_price":
+		return &FieldError{"labour_market", "can't be a posted price market"}
+	case s.LabourMarket == "matching" && s.Employment == nil:
+		return &FieldError{"employment", "required for a matching labour market"}
+	case !validSchedule(s.Schedule):
+		return &FieldError{"schedule", "unknown schedule " + s.Schedule}
+	}
+
+	if e := s.Employment; e != nil {
+		switch {
+		case e.Duration < 0:
+			return &FieldError{"employment.duration", "must not be negative"}
+		case s.LabourMarket == "matching" && e.MatchingEfficiency <= 0:
+			return &FieldError{"employment.matching_efficiency", "must be positive for a matching labour market"}
+		case s.LabourMarket == "matching" && (e.MatchingElasticity <= 0 || e.MatchingElasticity >= 1):
+			return &FieldError{"employment.matching_elasticity", "must be between 0 and 1 exclusive for a matching labour market"}
+		}
+	}
+
+	if e := s.EntryExit; e != nil {
+		switch {
+		case e.ExitAfter <= 0:
+			return &FieldError{"entry_exit.exit_after", "must be positive"}
+		case e.EntryMoney == 0:
+			return &FieldError{"entry_exit.entry_money", "must be positive"}
+		}
+	}
+
+	for name := range s.Goods {
+		good, err := goods.Parse(name)
+		if err != nil || good == goods.Labour {
+			return &FieldError{"goods." + name, "not a consumer good"}
+		}
+	}
+	for _, good := range goods.AllGoods {
+		g, ok := s.Goods[good.String()]
+		field := "goods." + good.String()
+		switch {
+		case !ok:
+			return &FieldError{field, "missing"}
+		case g.Firms < 0:
+			return &FieldError{field + ".firms", "must not be negative"}
+		case g.Tech <= 0:
+			return &FieldError{field + ".tech", "must be positive"}
+		// The firm's profit maximization only has a solution with decreasing returns to scale.
+		case g.Scale <= 0 || g.Scale >= 1:
+			return &FieldError{field + ".scale", "must be between 0 and 1 exclusive"}
+		case g.Share <= 0:
+			return &FieldError{field + ".share", "must be positive"}
+		case g.Spoilage < 0 || g.Spoilage > 1:
+			return &FieldError{field + ".spoilage", "must be between 0 and 1"}
+		case !validMarket(g.Market):
+			return &FieldError{field + ".market", "unknown market " + g.Market}
+		case g.Market == "matching":
+			return &FieldError{field + ".market", "only the labour market can be a matching market"}
+		case g.Market == "posted_price" && g.SampleSize <= 0:
+			return &FieldError{field + ".sample_size", "must be positive for a posted price market"}
+		}
+	}
+	return nil
+}
+
+// With gives a copy of the scenario with one parameter set to a value, checking
+// that the result is valid. Parameters are named by where they are in the
+// scenario file, the same way as in a FieldError, e.g. "elasticity" or
+// "goods.Grain.tech". The precision can't be changed this way, since it
+// applies to the whole program.
+func (s *Scenario) With(param string, value float64) (*Scenario, error) {
+	if param == "precision" {
+		return nil, &FieldError{param, "can't be changed"}
+	}
+
+	// Go through the file format, so that every parameter can be reached the
+	// same way and gets the same checks as when it's loaded.
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("scenario: %s", err)
+	}
+	tree := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("scenario: %s", err)
+	}
+
+	path := strings.Split(param, ".")
+	node := tree
+	for i, key := range path[:len(path)-1] {
+		child, ok := node[key].(map[string]interface{})
+		if !ok {
+			if node[key] != nil {
+				return nil, &FieldError{strings.Join(path[:i+1], "."), "not a section"}
+			}
+			child = map[string]interface{}{}
+			node[key] = child
+		}
+		node = child
+	}
+	node[path[len(path)-1]] = json.Number(strconv.FormatFloat(value, 'f', -1, 64))
+
+	if data, err = json.Marshal(tree); err != nil {
+		return nil, fmt.Errorf("scenario: %s", err)
+	}
+	dec = json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	changed := &Scenario{}
+	if err := dec.Decode(changed); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			return nil, &FieldError{param, "not a parameter"}
+		}
+		return nil, &FieldError{param, fmt.Sprintf("can't be %g: %s", value, err)}
+	}
+	if err := changed.Validate(); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// Good gets the settings for a single good.
+func (s *Scenario) Good(good goods.Good) GoodScenario {
+	return s.Goods[good.String()]
+}
+
+// Parameters builds the simulation parameters described by this scenario,
+// creating a fresh market for labour and each good.
+func (s *Scenario) Parameters() agents.Parameters {
+	params := agents.Parameters{
+		Increment:     s.Increment,
+		LabourMarket:  s.newMarket(s.LabourMarket, goods.Labour),
+		Elasticity:    s.Elasticity,
+		DissavingRate: s.DissavingRate,
+		Goods:         map[goods.Good]agents.GoodParameters{},
+	}
+	if s.Employment != nil {
+		params.Employment = &agents.Employment{Duration: s.Employment.Duration}
+	}
+	for _, good := range goods.AllGoods {
+		g := s.Good(good)
+		params.Goods[good] = agents.GoodParameters{
+			Tech:     g.Tech,
+			Scale:    g.Scale,
+			Share:    g.Share,
+			Spoilage: g.Spoilage,
+			Market:   s.newMarket(g.Market, good),
+		}
+	}
+	return params
+}
+
+// NewEntryExit builds the entry and exit rules described by this scenario, or
+// nil if the number of firms is fixed.
+func (s *Scenario) NewEntryExit() *agents.EntryExit {
+	if s.EntryExit == nil {
+		return nil
+	}
+	return &agents.EntryExit{
+		EntryProfit:  s.EntryExit.EntryProfit,
+		ExitAfter:    s.EntryExit.ExitAfter,
+		EntryMoney:   s.EntryExit.EntryMoney,
+		InitialWage:  s.InitialWage,
+		InitialPrice: s.InitialPrice,
+	}
+}
@@ -0,0 +1,171 @@
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+func TestDefaultFileMatchesDefault(t *testing.T) {
+	f, err := os.Open("../scenarios/default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	s, err := Parse(f)
+	if err != nil {
+		t.Fatalf("Parse() failed: %s", err)
+	}
+	if !reflect.DeepEqual(s, Default()) {
+		t.Errorf("got %v, want %v", s, Default())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		desc      string
+		modify    func(*Scenario)
+		wantField string
+	}{
+		{
+			"no workers",
+			func(s *Scenario) { s.Workers = 0 },
+			"workers",
+		},
+		{
+			"zero increment",
+			func(s *Scenario) { s.Increment = 0 },
+			"increment",
+		},
+		{
+			"missing good",
+			func(s *Scenario) { delete(s.Goods, "Meat") },
+			"goods.Meat",
+		},
+		{
+			"unknown good",
+			func(s *Scenario) { s.Goods["Gold"] = GoodScenario{} },
+			"goods.Gold",
+		},
+		{
+			"labour is not a consumer good",
+			func(s *Scenario) { s.Goods["Labour"] = GoodScenario{} },
+			"goods.Labour",
+		},
+		{
+			"increasing returns to scale",
+			func(s *Scenario) {
+				g := s.Goods["Grain"]
+				g.Scale = 1.5
+				s.Goods["Grain"] = g
+			},
+			"goods.Grain.scale",
+		},
+		{
+			"unknown market",
+			func(s *Scenario) {
+				g := s.Goods["Meat"]
+				g.Market = "bazaar"
+				s.Goods["Meat"] = g
+			},
+			"goods.Meat.market",
+		},
+		{
+			"unknown schedule",
+			func(s *Scenario) { s.Schedule = "whenever" },
+			"schedule",
+		},
+	} {
+		s := Default()
+		test.modify(s)
+		err := s.Validate()
+		fe, ok := err.(*FieldError)
+		if !ok {
+			t.Errorf("%s: got error %v, want a *FieldError", test.desc, err)
+			continue
+		}
+		if fe.Field != test.wantField {
+			t.Errorf("%s: got field %q, want %q", test.desc, fe.Field, test.wantField)
+		}
+	}
+}
+
+func TestParseRejectsUnknownFields(t *testing.T) {
+	_, err := Parse(strings.NewReader(`{"workerz": 10}`))
+	if err == nil {
+		t.Error("got no error for an unknown field")
+	}
+}
+
+func TestParsePrecision(t *testing.T) {
+	defer market.SetPrecision(0)
+
+	data, err := json.Marshal(Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte(`"initial_price":2`), []byte(`"precision":2,"initial_price":2.5`), 1)
+
+	s, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse() failed: %s", err)
+	}
+	if market.Precision() != 2 {
+		t.Errorf("got precision %d, want 2", market.Precision())
+	}
+	if s.InitialPrice != 250 || s.InitialWage != 10000 {
+		t.Errorf("got initial price %d and wage %d, want 250 and 10000 hundredths", s.InitialPrice, s.InitialWage)
+	}
+
+	data = bytes.Replace(data, []byte(`"initial_price":2.5`), []byte(`"initial_price":2.555`), 1)
+	if _, err := Parse(bytes.NewReader(data)); err == nil {
+		t.Error("got no error for a price with too many decimal places")
+	}
+}
+
+func TestWith(t *testing.T) {
+	base := Default()
+
+	s, err := base.With("goods.Grain.tech", 1200)
+	if err != nil {
+		t.Fatalf("With() failed: %s", err)
+	}
+	if got := s.Good(goods.Grain).Tech; got != 1200 {
+		t.Errorf("got grain tech %g, want 1200", got)
+	}
+	if base.Good(goods.Grain).Tech != 1000 {
+		t.Error("With() changed the original scenario")
+	}
+
+	if s, err = base.With("increment", 3); err != nil || s.Increment != market.NewPrice(3) {
+		t.Errorf("got increment %s and %v, want 3", s.Increment, err)
+	}
+	if s, err = base.With("elasticity", 1.5); err != nil || s.Elasticity != 1.5 {
+		t.Errorf("got elasticity %g and %v, want 1.5", s.Elasticity, err)
+	}
+
+	for _, test := range []struct {
+		param     string
+		value     float64
+		wantField string
+	}{
+		{"workerz", 10, "workerz"},
+		{"precision", 2, "precision"},
+		{"workers", 10.5, "workers"},
+		{"increment", 0.5, "increment"},
+		{"goods.Grain.scale", 1.5, "goods.Grain.scale"},
+		{"elasticity.x", 1, "elasticity"},
+	} {
+		_, err := base.With(test.param, test.value)
+		if fe, ok := err.(*FieldError); !ok || fe.Field != test.wantField {
+			t.Errorf("With(%q, %g): got %v, want an error for %s", test.param, test.value, err, test.wantField)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/robbrit/econerra/agents"
+	"github.com/robbrit/econerra/macro"
+	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/rng"
+	"github.com/robbrit/econerra/scenario"
+)
+
+// CheckpointVersion is the version of the checkpoint format that Save writes.
+// It goes up whenever the format changes, and Load only reads checkpoints of
+// this version.
+const CheckpointVersion = 1
+
+// A Checkpoint is the complete state of a simulation between cycles.
+type Checkpoint struct {
+	Version int `json:"version"`
+	// The scenario the simulation was set up from.
+	Scenario *scenario.Scenario `json:"scenario"`
+	// How many cycles have been run.
+	Cycle int `json:"cycle"`
+	// How much money there should be in the economy.
+	Money market.Price `json:"money"`
+	// Where the random order that agents act in is up to.
+	Rand rng.State `json:"rand"`
+	// The agents, and their IDs in the same order.
+	Firms     []agents.FirmState   `json:"firms"`
+	Workers   []agents.WorkerState `json:"workers"`
+	FirmIDs   []int                `json:"firm_ids"`
+	WorkerIDs []int                `json:"worker_ids"`
+	NextID    int                  `json:"next_id"`
+	// One snapshot for each market, in the same order as Markets.
+	Markets []market.Snapshot  `json:"markets"`
+	Macro   macro.TrackerState `json:"macro"`
+}
+
+// Save writes the complete state of the simulation, so that Load can pick it
+// up again and carry on exactly as if it had never stopped. It can only be
+// called between cycles, and every market has to be a market.Checkpointer.
+func (s *Simulation) Save(w io.Writer) error {
+	cp := Checkpoint{
+		Version:  CheckpointVersion,
+		Scenario: s.scenario,
+		Cycle:    s.cycle,
+		Money:    s.money,
+		Rand:     s.src.State(),
+		NextID:   s.nextID,
+		Macro:    s.macro.State(),
+	}
+	cp.Firms, cp.Workers = agents.Save(s.firms, s.workers, s.entryExit)
+	for _, f := range s.firms {
+		cp.FirmIDs = append(cp.FirmIDs, s.ids[f])
+	}
+	for _, w := range s.workers {
+		cp.WorkerIDs = append(cp.WorkerIDs, s.ids[w])
+	}
+	for _, mkt := range s.Markets {
+		c, ok := mkt.(market.Checkpointer)
+		if !ok {
+			return fmt.Errorf("checkpoint: %s market can't be saved", mkt.Good())
+		}
+		snap, err := c.Snapshot()
+		if err != nil {
+			return fmt.Errorf("checkpoint: %s market: %s", mkt.Good(), err)
+		}
+		cp.Markets = append(cp.Markets, snap)
+	}
+	return json.NewEncoder(w).Encode(cp)
+}
+
+// Load sets up a simulation from a checkpoint written by Save. Like
+// scenario.Parse, it sets the precision of the markets for the whole program.
+func Load(r io.Reader) (*Simulation, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The scenario sets the precision, which changes how prices are read, so
+	// it has to be read before everything else.
+	var header struct {
+		Version  int             `json:"version"`
+		Scenario json.RawMessage `json:"scenario"`
+	}
+	if err := json.Unmarshal(b, &header); err != nil {
+		return nil, fmt.Errorf("checkpoint: %s", err)
+	}
+	if header.Version != CheckpointVersion {
+		return nil, fmt.Errorf("checkpoint: version %d, expected %d", header.Version, CheckpointVersion)
+	}
+	scen, err := scenario.Parse(bytes.NewReader(header.Scenario))
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint: %s", err)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, fmt.Errorf("checkpoint: %s", err)
+	}
+	if len(cp.FirmIDs) != len(cp.Firms) || len(cp.WorkerIDs) != len(cp.Workers) {
+		return nil, fmt.Errorf("checkpoint: expected an ID for every agent")
+	}
+
+	s, err := New(scen)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint: %s", err)
+	}
+	if len(cp.Markets) != len(s.Markets) {
+		return nil, fmt.Errorf("checkpoint: %d markets, expected %d", len(cp.Markets), len(s.Markets))
+	}
+	for i, mkt := range s.Markets {
+		c, ok := mkt.(market.Checkpointer)
+		if !ok {
+			return nil, fmt.Errorf("checkpoint: %s market can't be restored", mkt.Good())
+		}
+		c.Restore(cp.Markets[i])
+	}
+
+	if s.firms, s.workers, err = agents.Restore(&s.Params, s.entryExit, cp.Firms, cp.Workers); err != nil {
+		return nil, fmt.Errorf("checkpoint: %s", err)
+	}
+	s.ids = map[Actor]int{}
+	for i, f := range s.firms {
+		s.ids[f] = cp.FirmIDs[i]
+	}
+	for i, w := range s.workers {
+		s.ids[w] = cp.WorkerIDs[i]
+	}
+	s.nextID = cp.NextID
+	s.buildActors()
+
+	s.src.Restore(cp.Rand)
+	s.macro = macro.RestoreTracker(cp.Macro)
+	s.cycle = cp.Cycle
+	s.money = cp.Money
+	if total := s.totalMoney(); total != s.money {
+		return nil, fmt.Errorf("checkpoint: agents hold %s, expected %s", total, s.money)
+	}
+	return s, nil
+}
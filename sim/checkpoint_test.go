@@ -0,0 +1,64 @@
+package sim
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/scenario"
+)
+
+func TestCheckpoint(t *testing.T) {
+	paths, err := filepath.Glob("../scenarios/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Some scenarios change the precision.
+	defer market.SetPrecision(0)
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			scen, err := scenario.Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scen.Workers = 100
+
+			want, err := mustNew(t, scen).Run(20)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s := mustNew(t, scen)
+			got, err := s.Run(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := s.Save(&buf); err != nil {
+				t.Fatalf("Save() failed: %s", err)
+			}
+			if s, err = Load(&buf); err != nil {
+				t.Fatalf("Load() failed: %s", err)
+			}
+			if s.Cycle() != 10 {
+				t.Errorf("got cycle %d after loading, want 10", s.Cycle())
+			}
+			rest, err := s.Run(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got = append(got, rest...); !reflect.DeepEqual(got, want) {
+				t.Error("the run picked up from a checkpoint differs from the one that never stopped")
+			}
+		})
+	}
+}
+
+func TestLoadChecksVersion(t *testing.T) {
+	if _, err := Load(bytes.NewBufferString(`{"version": 0}`)); err == nil {
+		t.Error("Load() accepted a checkpoint of the wrong version")
+	}
+}
@@ -0,0 +1,71 @@
+package sim
+
+import (
+	"fmt"
+
+	"github.com/robbrit/econerra/agents"
+	"github.com/robbrit/econerra/goods"
+)
+
+// A Stage is a step of a cycle: every agent acts in the stage's phase, in a
+// random order, then the markets for the stage's goods are reset, which is
+// when call auctions and other batch markets fill their orders.
+type Stage struct {
+	Phase agents.Phase
+	// The goods whose markets are reset at the end of the stage.
+	Markets []goods.Good
+}
+
+// A Schedule is the stages that make up a cycle, in order. Every market is
+// reset in exactly one stage.
+type Schedule []Stage
+
+// SinglePass is a schedule where every agent does everything in one pass
+// before every market is reset, so what agents do in the goods markets is
+// based on the last cycle's labour market.
+func SinglePass() Schedule {
+	return Schedule{
+		{Phase: agents.SinglePhase, Markets: append([]goods.Good{goods.Labour}, goods.AllGoods...)},
+	}
+}
+
+// Phased is a schedule where the labour market clears first, then firms
+// produce, then the goods markets clear, then agents see how they went.
+func Phased() Schedule {
+	return Schedule{
+		{Phase: agents.LabourPhase, Markets: []goods.Good{goods.Labour}},
+		{Phase: agents.ProductionPhase},
+		{Phase: agents.GoodsPhase, Markets: append([]goods.Good(nil), goods.AllGoods...)},
+		{Phase: agents.ConsumptionPhase},
+	}
+}
+
+// resets checks whether a stage resets the market for a good.
+func (s Stage) resets(good goods.Good) bool {
+	for _, g := range s.Markets {
+		if g == good {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks that every market is reset exactly once.
+func (s Schedule) validate(markets []goods.Good) error {
+	resets := map[goods.Good]int{}
+	for _, stage := range s {
+		for _, good := range stage.Markets {
+			resets[good]++
+		}
+	}
+	for _, good := range markets {
+		if resets[good] != 1 {
+			return fmt.Errorf("schedule resets the %s market %d times, expected once", good, resets[good])
+		}
+		delete(resets, good)
+	}
+	for good := range resets {
+		return fmt.Errorf("schedule resets the %s market, which doesn't exist", good)
+	}
+	return nil
+}
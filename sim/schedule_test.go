@@ -0,0 +1,76 @@
+package sim
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/agents"
+	"github.com/robbrit/econerra/goods"
+)
+
+func TestPhasedSchedule(t *testing.T) {
+	// In a single pass firms only have last cycle's workers to produce with, so
+	// nothing is sold in the first cycle. With phases they hire, produce and
+	// sell all in the same cycle.
+	for _, tc := range []struct {
+		schedule string
+		sells    bool
+	}{
+		{"single", false},
+		{"phased", true},
+	} {
+		scen := smallScenario()
+		scen.Schedule = tc.schedule
+		s := mustNew(t, scen)
+		res, err := s.Step()
+		if err != nil {
+			t.Fatalf("%s: %s", tc.schedule, err)
+		}
+		sold := false
+		for _, r := range res.Markets {
+			if r.Good == goods.Labour {
+				if r.Volume == 0 {
+					t.Errorf("%s: nobody was hired", tc.schedule)
+				}
+			} else if r.Volume > 0 {
+				sold = true
+			}
+		}
+		if sold != tc.sells {
+			t.Errorf("%s: got goods sold %t in the first cycle, want %t", tc.schedule, sold, tc.sells)
+		}
+	}
+}
+
+func TestScheduleClock(t *testing.T) {
+	scen := smallScenario()
+	scen.Schedule = "phased"
+	s := mustNew(t, scen)
+	if _, err := s.Step(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []agents.Clock{
+		{Cycle: 1, Phase: agents.LabourPhase, Elapsed: 1},
+		{Cycle: 1, Phase: agents.ProductionPhase, Elapsed: 1.25},
+		{Cycle: 1, Phase: agents.GoodsPhase, Elapsed: 1.5},
+		{Cycle: 1, Phase: agents.ConsumptionPhase, Elapsed: 1.75},
+	}
+	for stage, c := range want {
+		if got := s.clock(stage); got != c {
+			t.Errorf("stage %d: got clock %+v, want %+v", stage, got, c)
+		}
+	}
+}
+
+func TestScheduleMustResetEveryMarket(t *testing.T) {
+	for _, schedule := range []Schedule{
+		{{Phase: agents.SinglePhase, Markets: []goods.Good{goods.Labour}}},
+		append(SinglePass(), Stage{Phase: agents.ConsumptionPhase, Markets: []goods.Good{goods.Grain}}),
+	} {
+		s := mustNew(t, smallScenario())
+		s.Schedule = schedule
+		if _, err := s.Step(); err == nil {
+			t.Errorf("Step() ran with schedule %v", schedule)
+		}
+	}
+}
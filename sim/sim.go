@@ -0,0 +1,372 @@
+// Package sim runs the simulation: it owns the agents and the markets, and
+// steps them through cycles.
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/robbrit/econerra/agents"
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/macro"
+	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/rng"
+	"github.com/robbrit/econerra/scenario"
+)
+
+// An Actor is an agent that takes part in the simulation.
+type Actor interface {
+	// Act triggers the agent's decision process.
+	Act(*agents.Parameters, agents.Clock)
+	// TargetDemand gives the amount of a good this agent demands.
+	TargetDemand(goods.Good) market.Size
+	// TargetSupply gives the amount of a good this agent supplies.
+	TargetSupply(goods.Good) market.Size
+	// Money gets how much cash this agent has on hand.
+	Money() market.Price
+}
+
+// MarketResult summarizes what happened in a single market during a cycle.
+type MarketResult struct {
+	Good   goods.Good   `json:"good"`
+	Bid    market.Price `json:"bid"`
+	Ask    market.Price `json:"ask"`
+	Low    market.Price `json:"low"`
+	High   market.Price `json:"high"`
+	Volume market.Size  `json:"volume"`
+	// Prices of the first and last trades, and the volume-weighted average.
+	Open  market.Price `json:"open"`
+	Close market.Price `json:"close"`
+	VWAP  float64      `json:"vwap"`
+	// How many trades were made.
+	Trades int `json:"trades"`
+	// Total amount that all agents were trying to sell.
+	Supply market.Size `json:"supply"`
+	// Total amount that all agents were trying to buy.
+	Demand market.Size `json:"demand"`
+	// How many firms entered and exited the industry for this good.
+	Entries int `json:"entries"`
+	Exits   int `json:"exits"`
+	// What happened with employment contracts, only for the labour market.
+	agents.EmploymentEvents
+	// How many orders the market rejected, and why.
+	Rejections market.Rejections `json:"rejections"`
+	// The orders left open at the end of the cycle.
+	Depth *market.Depth `json:"depth,omitempty"`
+}
+
+// A CycleResult is everything that happened during a single cycle.
+type CycleResult struct {
+	Cycle   int
+	Markets []MarketResult
+	Macro   macro.Stats
+}
+
+// An Observer is notified as a simulation progresses.
+type Observer interface {
+	// OnMarket is called right after a market is reset at the end of a cycle.
+	OnMarket(cycle int, mkt market.Market) error
+	// OnCycleEnd is called with the results once the cycle is over.
+	OnCycleEnd(*CycleResult) error
+}
+
+// A CycleFunc is an Observer that only cares about the end of each cycle.
+type CycleFunc func(*CycleResult) error
+
+// OnMarket does nothing.
+func (fn CycleFunc) OnMarket(int, market.Market) error { return nil }
+
+// OnCycleEnd calls the function.
+func (fn CycleFunc) OnCycleEnd(res *CycleResult) error { return fn(res) }
+
+// A Simulation is a single run of the economy.
+type Simulation struct {
+	Params  agents.Parameters
+	Markets []market.Market
+	// How each cycle is split up, set from the scenario. It can be changed
+	// between cycles.
+	Schedule Schedule
+
+	scenario  *scenario.Scenario
+	firms     []*agents.Firm
+	workers   []*agents.Worker
+	actors    []Actor
+	ids       map[Actor]int
+	nextID    int
+	entryExit *agents.EntryExit
+	macro     *macro.Tracker
+
+	src       *rng.Source
+	rand      *rand.Rand
+	cycle     int
+	money     market.Price
+	observers []Observer
+}
+
+// New sets up a simulation as described by a scenario. Prices and sizes must
+// already have the scenario's precision, which loading it sets, since the
+// precision is shared by the whole program.
+func New(scen *scenario.Scenario) (*Simulation, error) {
+	if err := checkPrecision(scen); err != nil {
+		return nil, err
+	}
+
+	s := &Simulation{
+		Params:    scen.Parameters(),
+		scenario:  scen,
+		entryExit: scen.NewEntryExit(),
+		src:       rng.NewSource(scen.Seed),
+		ids:       map[Actor]int{},
+		macro:     macro.NewTracker(),
+	}
+	s.rand = rand.New(s.src)
+	s.Schedule = SinglePass()
+	if scen.Schedule == "phased" {
+		s.Schedule = Phased()
+	}
+
+	s.Markets = []market.Market{s.Params.LabourMarket}
+	for _, good := range goods.AllGoods {
+		s.Markets = append(s.Markets, s.Params.Goods[good].Market)
+	}
+
+	for _, good := range goods.AllGoods {
+		for i := 0; i < scen.Good(good).Firms; i++ {
+			s.firms = append(s.firms, agents.NewFirm(good, scen.InitialWage, scen.InitialPrice, scen.InitialFirmMoney))
+		}
+	}
+	for i := 0; i < scen.Workers; i++ {
+		s.workers = append(s.workers, agents.NewWorker(scen.InitialWage, scen.InitialPrice, scen.InitialWorkerMoney))
+	}
+	for _, f := range s.firms {
+		s.assignID(f)
+	}
+	for _, w := range s.workers {
+		s.assignID(w)
+	}
+	s.buildActors()
+	s.money = s.totalMoney()
+
+	return s, nil
+}
+
+// checkPrecision checks that prices and sizes have the scenario's precision,
+// so that a scenario isn't run with another one's.
+func checkPrecision(scen *scenario.Scenario) error {
+	if p := market.Precision(); scen.Precision != p {
+		return fmt.Errorf("scenario has a precision of %d, but prices and sizes have %d decimal places", scen.Precision, p)
+	}
+	return nil
+}
+
+// Actors lists all the agents in the economy, firms first.
+func (s *Simulation) Actors() []Actor { return s.actors }
+
+// Firms lists all the firms in the economy.
+func (s *Simulation) Firms() []*agents.Firm { return s.firms }
+
+// Workers lists all the workers in the economy.
+func (s *Simulation) Workers() []*agents.Worker { return s.workers }
+
+// ID gets a number that uniquely identifies an agent in the economy. IDs are
+// never reused, even once a firm has exited.
+func (s *Simulation) ID(a Actor) int { return s.ids[a] }
+
+// Cycle gets how many cycles have been run so far.
+func (s *Simulation) Cycle() int { return s.cycle }
+
+// Clock gets where the simulation is up to, as the agents will see it when
+// they next act.
+func (s *Simulation) Clock() agents.Clock { return s.clock(0) }
+
+// clock gets the clock for a stage of the current cycle.
+func (s *Simulation) clock(stage int) agents.Clock {
+	c := agents.Clock{Cycle: s.cycle, Elapsed: float64(s.cycle)}
+	if stage < len(s.Schedule) {
+		c.Phase = s.Schedule[stage].Phase
+		c.Elapsed += float64(stage) / float64(len(s.Schedule))
+	}
+	return c
+}
+
+// Scenario gets the scenario that the simulation was set up from.
+func (s *Simulation) Scenario() *scenario.Scenario { return s.scenario }
+
+// AddObserver registers an observer to be notified as the simulation runs.
+func (s *Simulation) AddObserver(o Observer) {
+	s.observers = append(s.observers, o)
+}
+
+// Run steps the simulation through n cycles, returning the results of each.
+func (s *Simulation) Run(n int) ([]*CycleResult, error) {
+	var results []*CycleResult
+	for i := 0; i < n; i++ {
+		res, err := s.Step()
+		if err != nil {
+			return results, err
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// Step runs a single cycle, one stage of the schedule at a time: every agent
+// acts in a random order, then the stage's markets are reset.
+func (s *Simulation) Step() (*CycleResult, error) {
+	var traded []goods.Good
+	for _, mkt := range s.Markets {
+		traded = append(traded, mkt.Good())
+	}
+	if err := s.Schedule.validate(traded); err != nil {
+		return nil, err
+	}
+	// Something else could have changed the precision since the simulation was
+	// set up.
+	if err := checkPrecision(s.scenario); err != nil {
+		return nil, err
+	}
+	if s.Params.Employment != nil {
+		s.Params.Employment.ResetEvents()
+	}
+
+	res := &CycleResult{Cycle: s.cycle, Markets: make([]MarketResult, len(s.Markets))}
+	for stage := range s.Schedule {
+		clock := s.clock(stage)
+		for _, i := range s.rand.Perm(len(s.actors)) {
+			s.actors[i].Act(&s.Params, clock)
+		}
+
+		for i, mkt := range s.Markets {
+			if !s.Schedule[stage].resets(mkt.Good()) {
+				continue
+			}
+			r, err := s.closeMarket(mkt)
+			if err != nil {
+				return nil, err
+			}
+			res.Markets[i] = r
+		}
+	}
+
+	res.Macro = s.macro.Update(&s.Params, s.firms, s.workers)
+
+	if s.entryExit != nil {
+		turnover := s.entryExit.Update(&s.Params, s.firms)
+		s.applyTurnover(turnover)
+		for i := range res.Markets {
+			res.Markets[i].Entries = len(turnover.Entered[res.Markets[i].Good])
+			res.Markets[i].Exits = len(turnover.Exited[res.Markets[i].Good])
+		}
+	}
+
+	if s.Params.Employment != nil {
+		for i := range res.Markets {
+			if res.Markets[i].Good == goods.Labour {
+				res.Markets[i].EmploymentEvents = s.Params.Employment.Events()
+			}
+		}
+	}
+
+	// Trades only move money around, they should never create or destroy it. The only
+	// money coming in or out of the economy is from firms entering or exiting.
+	if total := s.totalMoney(); total != s.money {
+		return nil, fmt.Errorf("money not conserved in cycle %d: expected %s, ended with %s", s.cycle, s.money, total)
+	}
+
+	s.cycle++
+	for _, o := range s.observers {
+		if err := o.OnCycleEnd(res); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// closeMarket resets a market at the end of its stage, and summarizes what
+// happened in it.
+func (s *Simulation) closeMarket(mkt market.Market) (MarketResult, error) {
+	mkt.Reset()
+	for _, o := range s.observers {
+		if err := o.OnMarket(s.cycle, mkt); err != nil {
+			return MarketResult{}, err
+		}
+	}
+
+	trades, depth := mkt.Trades(), mkt.Depth()
+	r := MarketResult{
+		Good:   mkt.Good(),
+		Bid:    mkt.Bid(),
+		Ask:    mkt.Ask(),
+		Low:    mkt.Low(),
+		High:   mkt.High(),
+		Volume: mkt.Volume(),
+		Open:   trades.Open(),
+		Close:  trades.Close(),
+		VWAP:   trades.VWAP(),
+		Trades: len(trades),
+		Depth:  &depth,
+
+		Rejections: mkt.Rejections(),
+	}
+	for _, a := range s.actors {
+		r.Supply = r.Supply.Add(a.TargetSupply(mkt.Good()))
+		r.Demand = r.Demand.Add(a.TargetDemand(mkt.Good()))
+	}
+	return r, nil
+}
+
+func (s *Simulation) assignID(a Actor) {
+	s.ids[a] = s.nextID
+	s.nextID++
+}
+
+func (s *Simulation) buildActors() {
+	s.actors = make([]Actor, 0, len(s.firms)+len(s.workers))
+	for _, f := range s.firms {
+		s.actors = append(s.actors, f)
+	}
+	for _, w := range s.workers {
+		s.actors = append(s.actors, w)
+	}
+}
+
+// applyTurnover removes the firms that exited and adds the ones that entered,
+// keeping track of the money they take with them or bring in.
+func (s *Simulation) applyTurnover(t agents.Turnover) {
+	exited := map[*agents.Firm]bool{}
+	for _, fs := range t.Exited {
+		for _, f := range fs {
+			exited[f] = true
+			s.money = s.money.Sub(f.Money())
+			delete(s.ids, f)
+		}
+	}
+
+	var remaining []*agents.Firm
+	for _, f := range s.firms {
+		if !exited[f] {
+			remaining = append(remaining, f)
+		}
+	}
+	for _, good := range goods.AllGoods {
+		for _, f := range t.Entered[good] {
+			remaining = append(remaining, f)
+			s.money = s.money.Add(f.Money())
+			s.assignID(f)
+		}
+	}
+
+	s.firms = remaining
+	s.buildActors()
+}
+
+// totalMoney counts all the cash held in the economy.
+func (s *Simulation) totalMoney() market.Price {
+	total := market.Price(0)
+	for _, a := range s.actors {
+		// Saturates rather than wrapping around, so that an overflow can't hide
+		// money going missing.
+		total = total.Add(a.Money())
+	}
+	return total
+}
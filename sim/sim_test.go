@@ -0,0 +1,134 @@
+package sim
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/scenario"
+)
+
+// Check every market's book as the simulations run.
+func TestMain(m *testing.M) {
+	market.Strict = true
+	os.Exit(m.Run())
+}
+
+func smallScenario() *scenario.Scenario {
+	scen := scenario.Default()
+	scen.Workers = 100
+	scen.Cycles = 20
+	return scen
+}
+
+// mustNew sets up a simulation, failing the test if it can't.
+func mustNew(t *testing.T, scen *scenario.Scenario) *Simulation {
+	t.Helper()
+	s, err := New(scen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRun(t *testing.T) {
+	s := mustNew(t, smallScenario())
+
+	observed := 0
+	s.AddObserver(CycleFunc(func(*CycleResult) error {
+		observed++
+		return nil
+	}))
+
+	results, err := s.Run(20)
+	if err != nil {
+		t.Fatalf("Run() failed: %s", err)
+	}
+	if len(results) != 20 || observed != 20 {
+		t.Fatalf("got %d results and %d observations, want 20 of each", len(results), observed)
+	}
+	if s.Cycle() != 20 {
+		t.Errorf("got cycle %d, want 20", s.Cycle())
+	}
+
+	for i, res := range results {
+		if res.Cycle != i {
+			t.Errorf("result %d has cycle %d", i, res.Cycle)
+		}
+		if got, want := len(res.Markets), len(goods.AllGoods)+1; got != want {
+			t.Errorf("cycle %d: got %d markets, want %d", i, got, want)
+		}
+	}
+}
+
+func TestRunIsDeterministic(t *testing.T) {
+	a, err := mustNew(t, smallScenario()).Run(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := mustNew(t, smallScenario()).Run(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Error("two runs of the same scenario gave different results")
+	}
+}
+
+func TestAgentsAdjustAfterFirstCycle(t *testing.T) {
+	scen := smallScenario()
+	s := mustNew(t, scen)
+	if c := s.Clock(); !c.First() || c.Elapsed != 0 {
+		t.Errorf("got clock %+v before running, want the first cycle", c)
+	}
+
+	if _, err := s.Step(); err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range s.Firms() {
+		if f.Wage() != scen.InitialWage || f.Price() != scen.InitialPrice {
+			t.Errorf("firm %d adjusted its wage to %s and price to %s in the first cycle", i, f.Wage(), f.Price())
+		}
+	}
+
+	if _, err := s.Step(); err != nil {
+		t.Fatal(err)
+	}
+	if c := s.Clock(); c.Cycle != 2 || c.Elapsed != 2 {
+		t.Errorf("got clock %+v after two cycles, want cycle 2", c)
+	}
+	for i, f := range s.Firms() {
+		if f.Wage() == scen.InitialWage && f.Price() == scen.InitialPrice {
+			t.Errorf("firm %d didn't adjust its wage or price in the second cycle", i)
+		}
+	}
+}
+
+func TestPrecisionMustMatch(t *testing.T) {
+	defer market.SetPrecision(0)
+
+	// A scenario made in code doesn't set the precision.
+	scen := smallScenario()
+	scen.Precision = 2
+	if _, err := New(scen); err == nil {
+		t.Error("New() accepted a scenario with a different precision")
+	}
+
+	if err := market.SetPrecision(2); err != nil {
+		t.Fatal(err)
+	}
+	s := mustNew(t, scen)
+	if _, err := s.Step(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Something else changes it part way through.
+	if err := market.SetPrecision(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Step(); err == nil {
+		t.Error("Step() ran after the precision changed")
+	}
+}